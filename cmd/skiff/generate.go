@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/nyambati/skiff/internal/template"
+	"github.com/nyambati/skiff/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -17,13 +18,22 @@ var generateCmd = &cobra.Command{
 	Long: `
 Generates terragrunt configurations files from manifests.
 
+The files written, left unchanged or skipped by a dry run are reported with
+their manifest and service, as JSON or YAML with --output.
+
 Example:
-  skiff generate --name my-manifest --labels env=prod,region=us-west-2`,
+  skiff generate --name my-manifest --labels env=prod,region=us-west-2
+  skiff generate --dry-run --output json`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := template.Render(cmd.Context(), flagManifestID, flagLabels, flagDryRun); err != nil {
+		report, err := template.Render(cmd.Context(), flagManifestID, flagLabels, flagDryRun)
+		if err != nil {
 			cmd.PrintErr(err)
 			os.Exit(1)
 		}
+
+		if err := template.WriteGenerateReport(cmd.OutOrStdout(), report, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
 	},
 }
 
@@ -33,7 +43,7 @@ func init() {
 		&flagManifestID, "manifest", "m", "", "name of the manifest used to generate terraform configurations",
 	)
 	generateCmd.Flags().StringVarP(
-		&flagLabels, "labels", "l", "", "labels to filter terraform configurations to apply to the list of accounts",
+		&flagLabels, "labels", "l", "", `label selector, e.g. "env in (prod,staging),team!=data || tier=edge"`,
 	)
 	generateCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "dry run, generate")
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/terragrunt"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd lists the runs recorded in the audit log
+var historyCmd = &cobra.Command{
+	Use:   "history [flags]",
+	Short: "lists the recorded terragrunt runs",
+	Long: `The history command lists the runs skiff run appended to the .skiff_history.jsonl
+audit log of the project, oldest first. Every record holds the command, the
+selectors, the services they resolved to with their exit status and duration,
+the git commit and the user. --since and --until take an RFC 3339 time, a date
+or a duration before now.
+
+Examples:
+  skiff history --service eks
+  skiff history --manifest workload --since 24h
+  skiff history --since 2025-01-01 --until 2025-02-01 --output json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		query := terragrunt.HistoryQuery{Manifest: flagManifestID, Service: flagServiceName}
+
+		now := time.Now()
+		var err error
+		if flagSince != "" {
+			if query.Since, err = terragrunt.ParseHistoryTime(flagSince, now); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+		}
+		if flagUntil != "" {
+			if query.Until, err = terragrunt.ParseHistoryTime(flagUntil, now); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+		}
+
+		records, err := terragrunt.ReadHistory(config.HistoryFile, query)
+		if err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+		if flagLimit > 0 && len(records) > flagLimit {
+			records = records[len(records)-flagLimit:]
+		}
+
+		if err := terragrunt.WriteHistory(cmd.OutOrStdout(), records, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "only runs of services of this manifest")
+	historyCmd.Flags().StringVarP(&flagServiceName, "service", "s", "", "only runs of this service, as name or manifest/service")
+	historyCmd.Flags().StringVar(&flagSince, "since", "", "only runs started at or after this time, e.g. 24h or 2025-01-01")
+	historyCmd.Flags().StringVar(&flagUntil, "until", "", "only runs started at or before this time")
+	historyCmd.Flags().IntVar(&flagLimit, "limit", 0, "only the latest runs, 0 for all")
+}
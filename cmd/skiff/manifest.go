@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/nyambati/skiff/internal/manifest"
+	"github.com/nyambati/skiff/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -11,16 +12,50 @@ var addAccountCmd = &cobra.Command{
 	Args:  cobra.MinimumNArgs(0),
 	Long: `The manifest command allows you to edit the manifest file.
 
+Without edit flags the manifest is opened in $EDITOR. The --set, --set-json,
+--unset and --from-file flags edit its metadata non-interactively instead.
+
 Examples:
   skiff edit manifest --manifest my-manifest --metadata env=production,account_id=12345
+  skiff edit manifest -m my-manifest --set env=staging --unset team --yes
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return manifest.EditManifest(cmd.Context(), flagManifestID, flagMetadata)
+		return manifest.EditManifest(cmd.Context(), flagManifestID, flagMetadata, editOptions())
+	},
+}
+
+// manifestCmd groups the read-only manifest commands
+var manifestCmd = &cobra.Command{
+	Use:   "manifest [list] [flags]",
+	Short: "inspects manifest files",
+	Args:  cobra.MinimumNArgs(0),
+}
+
+var listManifestCmd = &cobra.Command{
+	Use:   "list [flags]",
+	Short: "lists manifests and their metadata",
+	Long: `The list command prints every manifest with its metadata.
+
+Examples:
+  skiff manifest list
+  skiff manifest list --labels env=prod --output json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := manifest.ListManifests(cmd.Context(), cmd.OutOrStdout(), flagManifestID, flagLabels, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
 	},
 }
 
 func init() {
 	addAccountCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "manifest identifier ")
 	addAccountCmd.Flags().StringVar(&flagMetadata, "metadata", "", "manifestmetadata")
+	addEditFlags(addAccountCmd, "metadata")
 	addAccountCmd.MarkFlagRequired("manifest")
+
+	rootCmd.AddCommand(manifestCmd)
+	manifestCmd.AddCommand(listManifestCmd)
+	listManifestCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest to list")
+	listManifestCmd.Flags().StringVarP(&flagLabels, "labels", "l", "", `label selector matched against the manifest metadata, e.g. "env in (prod,staging)"`)
 }
@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/manifest"
+	"github.com/nyambati/skiff/internal/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -26,8 +28,47 @@ var (
 	flagMetadata        string
 	flagArgs            string
 	flagPath            string = "skiff"
+	flagSet             []string
+	flagSetJSON         []string
+	flagUnset           []string
+	flagFromFile        string
+	flagYes             bool
+	flagToManifest      string
+	flagMoveGenerated   bool
+	flagOutput          string
+	flagStrategy        string
+	flagFrom            string
+	flagApply           bool
+	flagScript          string
+	flagUpdate          bool
+	flagMarkdown        string
+	flagAllowDestroy    bool
+	flagForceProtected  bool
+	flagSince           string
+	flagUntil           string
+	flagLimit           int
 )
 
+// editOptions collects the non-interactive edit flags shared by the edit commands.
+func editOptions() manifest.EditOptions {
+	return manifest.EditOptions{
+		Set:      flagSet,
+		SetJSON:  flagSetJSON,
+		Unset:    flagUnset,
+		FromFile: flagFromFile,
+		Yes:      flagYes,
+	}
+}
+
+// addEditFlags registers the non-interactive edit flags on cmd.
+func addEditFlags(cmd *cobra.Command, target string) {
+	cmd.Flags().StringArrayVar(&flagSet, "set", nil, "set a value at a dotted path of the "+target+", e.g. inputs.cidr=10.0.0.0/16")
+	cmd.Flags().StringArrayVar(&flagSetJSON, "set-json", nil, "set a JSON value at a dotted path of the "+target+`, e.g. inputs.subnets='["a","b"]'`)
+	cmd.Flags().StringArrayVar(&flagUnset, "unset", nil, "remove the value at a dotted path of the "+target+", e.g. labels.team")
+	cmd.Flags().StringVar(&flagFromFile, "from-file", "", "replace the "+target+" with the contents of a YAML file")
+	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "apply the changes without prompting")
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "skiff",
 	Short: "A tool to generate and apply Terragrunt configurations from YAML manifests",
@@ -36,10 +77,14 @@ and apply infrastructure using a declarative YAML format and Terragrunt.`,
 	CompletionOptions: cobra.CompletionOptions{HiddenDefaultCmd: true},
 
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := utils.ValidateOutput(flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
 		cfg, err := config.New(cmd.Name())
 		if err != nil {
 			if strings.Contains(err.Error(), "Not Found") {
-				cmd.Println("❌ Missing .skiff file. Run `skiff init` to create one ")
+				cmd.PrintErrln("❌ Missing .skiff file. Run `skiff init` to create one ")
 				os.Exit(1)
 			}
 			logrus.Error(err)
@@ -61,6 +106,10 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "verbose mode")
 	rootCmd.PersistentFlags().BoolVarP(&flagForce, "force", "f", false, "force overwrite")
+	rootCmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", utils.OutputText, "output format, one of text, json or yaml")
+
+	// logs go to stderr so the output of a command can be parsed from stdout
+	logrus.SetOutput(os.Stderr)
 
 	logrus.SetFormatter(&logrus.TextFormatter{
 		DisableTimestamp: true,
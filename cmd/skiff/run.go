@@ -4,10 +4,18 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bytes"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/selector"
 	"github.com/nyambati/skiff/internal/terragrunt"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -15,18 +23,116 @@ import (
 var runCmd = &cobra.Command{
 	Use:   "run [plan,apply,destroy]",
 	Short: "runs terragrunt command for specified manifest or services",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Runs a terragrunt command in the folder of every selected service, after
+the services it depends on (before them for destroy). Each service is reported
+with its exit code and duration, as JSON or YAML with --output; terragrunt
+output then goes to stderr.
+
+Plans are saved with -out, to skiff.tfplan in the service folder unless the
+arguments set their own, and converted with show -json, and the report ends
+with the resources each service adds, changes, destroys and replaces, totals
+per manifest and the resources to destroy. --markdown writes the same summary
+as Markdown, for example for a pull request comment.
+
+Destroy needs --allow-destroy and the name of every manifest typed as
+confirmation, then runs with -auto-approve. Apply plans every service first and applies the saved plan; a
+plan deleting or replacing resources needs the same. Services marked
+protected: true, directly or through their catalog type, and services matching
+a protected selector of .skiff are refused unless --force-protected is given.
+
+Timeouts and retries are set under run in .skiff and per catalog type:
+
+  run:
+    timeout: 30m
+    retries: 2
+    retry_delay: 10s
+    retry_on: ["Error acquiring the state lock"]
+
+A failed attempt is retried when its output matches retry_on, by default
+state lock, provider download and throttling errors. Ctrl-C interrupts the
+running terragrunt so it can release the state lock, and skips the services
+left.
+
+Every run is appended to the .skiff_history.jsonl audit log of the project,
+see skiff history.
+
+Examples:
+  skiff run plan --manifest my-manifest --labels env=prod
+  skiff run plan --labels env=prod --markdown plan.md
+  skiff run apply --manifest my-manifest --output json
+  skiff run destroy --manifest my-manifest --allow-destroy
+`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := terragrunt.Run(cmd.Context(), args[0], flagManifestID, flagLabels, strings.Split(flagArgs, ","), flagDryRun); err != nil {
+		// fail fast on a malformed selector before any terragrunt command runs
+		if _, err := selector.Parse(flagLabels); err != nil {
+			cmd.PrintErr(err)
+			os.Exit(1)
+		}
+
+		// terragrunt output only shares stdout with the text report
+		output := cmd.ErrOrStderr()
+		if flagOutput == utils.OutputText || flagOutput == utils.OutputTable {
+			output = cmd.OutOrStdout()
+		}
+
+		opts := terragrunt.RunOptions{
+			Command:        args[0],
+			Args:           strings.Split(flagArgs, ","),
+			ManifestID:     flagManifestID,
+			Labels:         flagLabels,
+			DryRun:         flagDryRun,
+			AllowDestroy:   flagAllowDestroy,
+			ForceProtected: flagForceProtected,
+			Output:         output,
+			Input:          cmd.InOrStdin(),
+		}
+
+		// an interrupt is forwarded to terragrunt so it can release the state lock
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		start := time.Now()
+		report, err := terragrunt.RunServices(ctx, opts)
+
+		// the audit trail should not fail the run it records
+		record := terragrunt.NewHistoryRecord(opts, report, err, start)
+		if err := terragrunt.AppendHistory(config.HistoryFile, record); err != nil {
+			logrus.Warnf("failed to record the run in the history: %v", err)
+		}
+
+		if err != nil {
 			cmd.PrintErr(err)
 			os.Exit(1)
 		}
+
+		if err := terragrunt.WriteReport(cmd.OutOrStdout(), report, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if flagMarkdown != "" {
+			var markdown bytes.Buffer
+			if err := terragrunt.WritePlanMarkdown(&markdown, report); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+			if err := utils.WriteFileAtomic(flagMarkdown, markdown.Bytes(), 0644); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+		}
+
+		if report.Failed() {
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
-	runCmd.Flags().StringVarP(&flagLabels, "labels", "l", "", "labels to filter terraform configurations to apply to the list of accounts")
+	runCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest to run")
+	runCmd.Flags().StringVarP(&flagLabels, "labels", "l", "", `label selector, e.g. "env in (prod,staging),team!=data || tier=edge"`)
 	runCmd.Flags().StringVarP(&flagArgs, "args", "a", "", "additional arguments to pass to terragrunt")
 	runCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "dry run mode")
+	runCmd.Flags().StringVar(&flagMarkdown, "markdown", "", "write the plan summary as Markdown to this file")
+	runCmd.Flags().BoolVar(&flagAllowDestroy, "allow-destroy", false, "allow destroy, and apply of plans deleting resources, after typing the manifest name")
+	runCmd.Flags().BoolVar(&flagForceProtected, "force-protected", false, "allow destroying resources of protected services")
 }
@@ -15,11 +15,17 @@ var addServiceCmd = &cobra.Command{
 	Short: "edits specific service in manifest file",
 	Long: `The service command allows you to edit a specific service in the manifest file.
 
+Without edit flags the service is opened in $EDITOR. The --set, --set-json,
+--unset and --from-file flags edit it non-interactively instead.
+
 Examples:
   skiff edit service --manifest my-manifest --service my-service
+  skiff edit service -m my-manifest -s vpc --set inputs.cidr=10.0.0.0/16 --yes
+  skiff edit service -m my-manifest -s vpc --set-json inputs.subnets='["10.0.1.0/24"]' --unset labels.team
+  skiff edit service -m my-manifest -s vpc --from-file service.yaml --yes
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := manifest.AddService(cmd.Context(), flagManifestID, flagServiceName); err != nil {
+		if err := manifest.AddService(cmd.Context(), flagManifestID, flagServiceName, editOptions()); err != nil {
 			utils.PrintErrorAndExit(err)
 		}
 	},
@@ -29,6 +35,105 @@ func init() {
 	editCmd.AddCommand(addServiceCmd)
 	addServiceCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest file")
 	addServiceCmd.Flags().StringVarP(&flagServiceName, "service", "s", "", "name of the service in the manifest file")
+	addEditFlags(addServiceCmd, "service")
 	addServiceCmd.MarkFlagRequired("manifest")
 	addServiceCmd.MarkFlagRequired("service")
+
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(listServiceCmd)
+	serviceCmd.AddCommand(removeServiceCmd)
+	serviceCmd.AddCommand(moveServiceCmd)
+
+	listServiceCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest to list services from")
+	listServiceCmd.Flags().StringVarP(&flagLabels, "labels", "l", "", `label selector, e.g. "env in (prod,staging),team!=data || tier=edge"`)
+
+	removeServiceCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest file")
+	removeServiceCmd.MarkFlagRequired("manifest")
+
+	moveServiceCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest file")
+	moveServiceCmd.Flags().StringVar(&flagToManifest, "to-manifest", "", "name of the manifest to move the service to")
+	moveServiceCmd.Flags().BoolVar(&flagMoveGenerated, "move-generated", false, "relocate the already generated folder")
+	moveServiceCmd.MarkFlagRequired("manifest")
+}
+
+// serviceCmd groups the commands that manage services across manifests
+var serviceCmd = &cobra.Command{
+	Use:   "service [list|rm|mv] [flags]",
+	Short: "manages services in manifest files",
+	Args:  cobra.MinimumNArgs(0),
+}
+
+var listServiceCmd = &cobra.Command{
+	Use:   "list [flags]",
+	Short: "lists services with their resolved type, version and target path",
+	Long: `The list command prints every service with its manifest, type, version,
+region, scope, target path and labels.
+
+Examples:
+  skiff service list
+  skiff service list --manifest my-manifest --labels env=prod --output yaml
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := manifest.ListServices(cmd.Context(), cmd.OutOrStdout(), flagManifestID, flagLabels, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
+}
+
+var removeServiceCmd = &cobra.Command{
+	Use:   "rm <service> [flags]",
+	Short: "removes a service from a manifest",
+	Long: `The rm command removes a service from a manifest.
+
+Services that depend on the removed service block the removal unless --force
+is given, in which case their dependency references are removed as well. The
+generated folder is left in place.
+
+Examples:
+  skiff service rm vpc --manifest my-manifest
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := manifest.RemoveService(cmd.Context(), flagManifestID, args[0], flagForce); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
+}
+
+var moveServiceCmd = &cobra.Command{
+	Use:   "mv <service> [new-name] [flags]",
+	Short: "renames a service or moves it to another manifest",
+	Long: `The mv command renames a service and/or moves it to another manifest.
+
+Dependency references in the manifest are updated to the new name. When the
+target folder changes, --move-generated relocates the already generated folder
+and the state migration steps are printed.
+
+Dependencies only resolve within a manifest. A service moves to another
+manifest only when its dependencies exist there, and services depending on it
+block the move unless --force, which removes their dependency on it.
+
+Examples:
+  skiff service mv vpc network --manifest my-manifest
+  skiff service mv vpc --manifest my-manifest --to-manifest shared-services
+  skiff service mv vpc network -m my-manifest --move-generated
+`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		newName := ""
+		if len(args) == 2 {
+			newName = args[1]
+		}
+
+		opts := manifest.MoveOptions{
+			ToManifest:    flagToManifest,
+			MoveGenerated: flagMoveGenerated,
+			Force:         flagForce,
+		}
+
+		if err := manifest.MoveService(cmd.Context(), flagManifestID, args[0], newName, opts); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
 }
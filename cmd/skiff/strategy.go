@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/nyambati/skiff/internal/strategy"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// strategyCmd groups the layout strategy commands
+var strategyCmd = &cobra.Command{
+	Use:   "strategy [list|show|preview|migrate] [flags]",
+	Short: "inspects layout strategies",
+	Long: `Strategies decide where the terragrunt configuration of each service is
+generated. Named strategies live in the strategies folder and are selected
+with strategy.name in .skiff or with the strategy field of a manifest.`,
+	Args: cobra.MinimumNArgs(0),
+}
+
+var listStrategyCmd = &cobra.Command{
+	Use:   "list [flags]",
+	Short: "lists the named strategies",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := strategy.WriteList(cmd.Context(), cmd.OutOrStdout(), flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
+}
+
+var showStrategyCmd = &cobra.Command{
+	Use:   "show [name] [flags]",
+	Short: "shows a strategy and its path template",
+	Long: `The show command prints a named strategy, or the strategy configured in
+.skiff when no name is given.
+
+Examples:
+  skiff strategy show
+  skiff strategy show group-first
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		if err := strategy.Show(cmd.Context(), cmd.OutOrStdout(), name, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
+}
+
+var previewStrategyCmd = &cobra.Command{
+	Use:   "preview [flags]",
+	Short: "shows where services land without generating",
+	Long: `The preview command resolves the target path of every service and prints
+the resulting directory tree. It flags services that resolve to the same
+folder and paths that leave the terragrunt output folder. With --strategy the
+services are resolved with another strategy, a file or a named strategy, and
+the services whose folder would move are flagged too.
+
+Examples:
+  skiff strategy preview
+  skiff strategy preview --strategy ./group-first.yaml
+  skiff strategy preview --strategy group-first --manifest my-manifest --output json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		preview, err := strategy.BuildPreview(cmd.Context(), flagManifestID, flagLabels, flagStrategy)
+		if err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if err := strategy.WritePreview(cmd.OutOrStdout(), preview, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+	},
+}
+
+var migrateStrategyCmd = &cobra.Command{
+	Use:   "migrate --from <strategy> [flags]",
+	Short: "moves generated folders to the current strategy layout",
+	Long: `The migrate command moves the generated folders of every service from the
+layout of a previous strategy to the one configured now. Change the strategy
+first, then pass the previous one with --from, as a file, a strategy name or a
+path template.
+
+Nothing is moved unless --apply is set. The report lists the remote state
+keys that change with the folders, rendered from the backend key template, or
+derived from path_relative_to_include() when no backend is configured.
+Use --script instead of --apply to write a shell script that moves each
+folder together with its state. Moved folders keep the relative paths
+rendered for their old depth, run skiff generate after --apply; the script
+generates them before pushing the state.
+
+Examples:
+  skiff strategy migrate --from '{{ var.account_id }}/{{ var.region }}/{{ var.service }}'
+  skiff strategy migrate --from account-region-service --script migrate.sh
+  skiff strategy migrate --from ./old-strategy.yaml --manifest my-manifest --apply
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		migration, err := strategy.PlanMigration(cmd.Context(), flagManifestID, flagLabels, flagFrom)
+		if err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if flagApply {
+			if err := strategy.ApplyMigration(migration); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+		}
+
+		if err := strategy.WriteMigration(cmd.OutOrStdout(), migration, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if flagScript != "" {
+			var script bytes.Buffer
+			if err := strategy.WriteMigrationScript(&script, migration); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+			if err := os.WriteFile(flagScript, script.Bytes(), 0755); err != nil {
+				utils.PrintErrorAndExit(err)
+			}
+			logrus.Infof("✅ migration script written to %s\n", flagScript)
+			return
+		}
+
+		if !flagApply {
+			logrus.Info("ℹ️  dry run, rerun with --apply to move the folders\n")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(strategyCmd)
+	strategyCmd.AddCommand(listStrategyCmd)
+	strategyCmd.AddCommand(showStrategyCmd)
+	strategyCmd.AddCommand(previewStrategyCmd)
+	strategyCmd.AddCommand(migrateStrategyCmd)
+
+	previewStrategyCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest to preview")
+	previewStrategyCmd.Flags().StringVarP(&flagLabels, "labels", "l", "", "label selector for the services to preview")
+	previewStrategyCmd.Flags().StringVar(&flagStrategy, "strategy", "", "strategy file, name or path template to preview instead of the configured one")
+
+	migrateStrategyCmd.Flags().StringVar(&flagFrom, "from", "", "previous strategy, a file, a strategy name or a path template")
+	migrateStrategyCmd.Flags().StringVarP(&flagManifestID, "manifest", "m", "", "name of the manifest to migrate")
+	migrateStrategyCmd.Flags().StringVarP(&flagLabels, "labels", "l", "", "label selector for the services to migrate")
+	migrateStrategyCmd.Flags().BoolVar(&flagApply, "apply", false, "move the folders instead of only reporting them")
+	migrateStrategyCmd.Flags().StringVar(&flagScript, "script", "", "write a shell script that moves the folders and their state")
+	migrateStrategyCmd.MarkFlagRequired("from")
+	migrateStrategyCmd.MarkFlagsMutuallyExclusive("apply", "script")
+}
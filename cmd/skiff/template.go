@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/nyambati/skiff/internal/template"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd groups the template checking commands
+var templateCmd = &cobra.Command{
+	Use:   "template [lint|test] [flags]",
+	Short: "checks templates without generating",
+	Long: `The template commands check the templates folder, and the default library,
+without rendering any manifest.`,
+	Args: cobra.MinimumNArgs(0),
+}
+
+var lintTemplateCmd = &cobra.Command{
+	Use:   "lint [flags]",
+	Short: "parses every template and checks its references",
+	Long: `The lint command parses every template and reports syntax errors, unknown
+functions, var.<key> references to keys the template context does not
+provide and references to templates or service types that do not exist.
+Metadata keys of the manifests and service labels are valid keys. It exits
+with status 1 when there are issues.
+
+Examples:
+  skiff template lint
+  skiff template lint --output json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		report, err := template.Lint(cmd.Context())
+		if err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if err := template.WriteLint(cmd.OutOrStdout(), report, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if len(report.Issues) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var testTemplateCmd = &cobra.Command{
+	Use:   "test [flags]",
+	Short: "renders templates against fixtures and compares golden files",
+	Long: `The test command renders every fixture of templates/testdata and compares
+the output with the golden file next to it. A fixture is a YAML file naming
+the template and the context to render it with:
+
+  # templates/testdata/eks.yaml
+  template: terragrunt.default.tmpl
+  manifest: workload
+  metadata:
+    account_id: "123"
+  path: 123/us-east-1/eks
+  context:
+    service: eks
+    region: us-east-1
+    terraform:
+      source: github.com/org/eks?ref=1.0.0
+    body:
+      inputs:
+        cluster_name: platform
+
+Its golden file is templates/testdata/eks.golden. With --update the golden
+files are written from the output instead. It exits with status 1 when a
+fixture fails.
+
+Examples:
+  skiff template test
+  skiff template test --update
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := template.RunTests(cmd.Context(), flagUpdate)
+		if err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if err := template.WriteTestResults(cmd.OutOrStdout(), results, flagOutput); err != nil {
+			utils.PrintErrorAndExit(err)
+		}
+
+		if template.TestsFailed(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(lintTemplateCmd)
+	templateCmd.AddCommand(testTemplateCmd)
+
+	testTemplateCmd.Flags().BoolVar(&flagUpdate, "update", false, "write the golden files from the rendered output")
+}
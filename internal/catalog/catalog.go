@@ -7,11 +7,14 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
 	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
 	"github.com/nyambati/skiff/internal/types"
 	"github.com/nyambati/skiff/internal/utils"
 	"github.com/sirupsen/logrus"
@@ -36,16 +39,79 @@ func (c *Catalog) Read(path string) error {
 	if err != nil {
 		return err
 	}
-	return yaml.Unmarshal(buff, &c)
+	if err := yaml.Unmarshal(buff, &c); err != nil {
+		return err
+	}
+	c.raw = buff
+	return nil
 }
 
 func (c *Catalog) Write(path string, force bool) error {
-	buff, err := c.ToYAML()
+	buff, err := c.Bytes()
 	if err != nil {
 		return err
 	}
 
-	return utils.WriteFile(path, utils.PrependWatermark(string(buff), config.ToolName))
+	if err := utils.WriteFile(path, buff); err != nil {
+		return err
+	}
+	c.raw = buff
+	return nil
+}
+
+// Bytes returns the catalog document with the in-memory changes applied to
+// the file it was read from. Only the service types that differ from the
+// original are rewritten, leaving comments, key order and formatting of the
+// remaining types untouched. Catalogs that were not read from disk are fully
+// marshalled with the skiff watermark.
+func (c *Catalog) Bytes() ([]byte, error) {
+	if len(c.raw) == 0 {
+		buff, err := c.ToYAML()
+		if err != nil {
+			return nil, err
+		}
+		return utils.PrependWatermark(string(buff), config.ToolName), nil
+	}
+
+	var original Catalog
+	if err := yaml.Unmarshal(c.raw, &original); err != nil {
+		return nil, err
+	}
+
+	doc := c.raw
+	var err error
+
+	if c.APIVersion != original.APIVersion {
+		if doc, err = utils.SetYAMLValue(doc, c.APIVersion, "apiVersion"); err != nil {
+			return nil, err
+		}
+	}
+
+	names := slices.Sorted(maps.Keys(original.Types))
+	for _, name := range slices.Sorted(maps.Keys(c.Types)) {
+		if _, ok := original.Types[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		svcType, exists := c.Types[name]
+		originalType, existed := original.Types[name]
+
+		switch {
+		case !exists:
+			doc, err = utils.DeleteYAMLValue(doc, "types", name)
+		case existed && reflect.DeepEqual(svcType, originalType):
+			continue
+		default:
+			doc, err = utils.SetYAMLValue(doc, svcType, "types", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
 }
 
 func NewCatalog() *Catalog {
@@ -118,15 +184,19 @@ func (s *Service) Reconcile(metadata types.Metadata) *Service {
 		s.Labels = map[string]any{}
 	}
 
-	maps.Copy(metadata, s.Labels)
+	// copy so that labels of one service do not leak into the shared
+	// manifest metadata and from there into other services
+	labels := map[string]any{}
+	maps.Copy(labels, metadata)
+	maps.Copy(labels, s.Labels)
 
 	if len(s.Inputs) == 0 {
 		s.Inputs = map[string]any{}
 	}
 
 	s.Inputs[config.RegionKey] = s.Region
-	s.Inputs[config.TagsKey] = metadata
-	s.Labels = metadata
+	s.Inputs[config.TagsKey] = labels
+	s.Labels = labels
 	return s
 }
 
@@ -136,6 +206,7 @@ func (s *Service) buildStrategyContext(svcName string, metadata types.Metadata)
 		config.RegionKey:  s.Region,
 		config.TypeKey:    s.Type,
 		config.GroupKey:   s.ResolvedType.Group,
+		config.ScopeKey:   s.Scope,
 	}
 
 	maps.Copy(context, metadata)
@@ -144,6 +215,33 @@ func (s *Service) buildStrategyContext(svcName string, metadata types.Metadata)
 	return context
 }
 
+// TemplateContextKeys are the keys BuildTemplateContext sets besides the
+// metadata and labels. RemoteStateKey and LevelsKey are only set when a
+// backend or levels are configured.
+var TemplateContextKeys = []string{
+	config.ServiceKey,
+	config.RegionKey,
+	config.TypeKey,
+	config.GroupKey,
+	config.InputsKey,
+	config.DependencyKey,
+	config.VersionKey,
+	config.ScopeKey,
+	config.TerraformKey,
+	config.BodyKey,
+	config.RemoteStateKey,
+	config.LevelsKey,
+}
+
+// LevelContextKeys are the keys ResolveLevels sets for level templates
+// besides the metadata.
+var LevelContextKeys = []string{
+	config.RegionKey,
+	config.GroupKey,
+	config.ScopeKey,
+	config.LevelKey,
+}
+
 // BuildTemplateContext creates a TemplateContext for the service based on the provided
 // service name, metadata, and resolved service type. The TemplateContext is used to
 // render the service's Terragrunt configuration file.
@@ -161,7 +259,15 @@ func (s *Service) buildStrategyContext(svcName string, metadata types.Metadata)
 //   - config.TerraformKey: a map containing the source of the Terraform module
 //     as a key-value pair (config.SourceKey)
 //   - config.BodyKey: a map containing the dependencies and inputs of the service
-//     as key-value pairs (config.DependencyKey and config.InputsKey)
+//     as key-value pairs (config.DependencyKey and config.InputsKey), plus the
+//     resolved providers (config.ProvidersKey) when there are any
+//
+// When a backend is configured, config.RemoteStateKey holds the remote_state
+// settings of the service: backend, config with the state key and generate.
+//
+// When the strategy defines levels, config.LevelsKey maps every level name to
+// the path of its file relative to the service folder, ready for include or
+// read_terragrunt_config.
 //
 // The generated TemplateContext will also contain any additional metadata and
 // labels that are provided.
@@ -171,7 +277,7 @@ func (s *Service) BuildTemplateContext(serviceName string, metadata types.Metada
 		config.RegionKey:     s.Region,
 		config.TypeKey:       s.Type,
 		config.GroupKey:      s.ResolvedType.Group,
-		config.InputsKey:     s.Inputs,
+		config.InputsKey:     map[string]any(s.Inputs),
 		config.DependencyKey: s.Dependencies,
 		config.VersionKey:    s.ResolvedType.Version,
 		config.ScopeKey:      s.Scope,
@@ -180,10 +286,34 @@ func (s *Service) BuildTemplateContext(serviceName string, metadata types.Metada
 		},
 		config.BodyKey: map[string]interface{}{
 			config.DependencyKey: s.Dependencies,
-			config.InputsKey:     s.Inputs,
+			config.InputsKey:     map[string]any(s.Inputs),
 		},
 	}
 
+	if len(s.ResolvedProviders) > 0 {
+		providers := map[string]interface{}{}
+		for name, provider := range s.ResolvedProviders {
+			providers[name] = provider.ToMap(name)
+		}
+		ctx[config.BodyKey].(map[string]interface{})[config.ProvidersKey] = providers
+	}
+
+	if s.ResolvedBackend != nil {
+		ctx[config.RemoteStateKey] = remoteState(s.ResolvedBackend)
+	}
+
+	if len(s.ResolvedLevels) > 0 {
+		levels := map[string]any{}
+		for _, level := range s.ResolvedLevels {
+			relPath, err := filepath.Rel(s.ResolvedTargetPath, filepath.Join(level.Path, level.File))
+			if err != nil {
+				return err
+			}
+			levels[level.Name] = filepath.ToSlash(relPath)
+		}
+		ctx[config.LevelsKey] = levels
+	}
+
 	maps.Copy(ctx, metadata)
 	maps.Copy(ctx, s.Labels)
 
@@ -203,21 +333,156 @@ func (s *Service) ResolveTargetPath(
 
 	strategyContext := s.buildStrategyContext(svcName, metadata)
 
+	resolvedPath, err := renderPath(cfg.Strategy.Template, strategyContext)
+	if err != nil {
+		return err
+	}
+
+	if reason := utils.ValidatePath(resolvedPath); reason != "" {
+		return skiff.NewInvalidTargetPathError(svcName, resolvedPath, reason)
+	}
+
+	s.ResolvedTargetPath = resolvedPath
+	return nil
+}
+
+// ResolveLevels resolves the folder of every level of the strategy for the
+// service. Level paths are rendered with the same variables as the target
+// path, an empty path being the terragrunt output folder itself. The level
+// templates are rendered with the manifest metadata plus the region, group and
+// scope of the service, so a level file stays identical for every service that
+// shares its folder.
+func (s *Service) ResolveLevels(
+	ctx context.Context,
+	svcName string,
+	metadata types.Metadata,
+) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	strategyContext := s.buildStrategyContext(svcName, metadata)
+	levels := make([]ResolvedLevel, 0, len(cfg.Strategy.Levels))
+
+	for _, level := range cfg.Strategy.Levels {
+		levelPath, err := renderPath(level.Path, strategyContext)
+		if err != nil {
+			return fmt.Errorf("failed to resolve level %s: %w", level.Name, err)
+		}
+
+		if levelPath != "" {
+			if reason := utils.ValidatePath(levelPath); reason != "" {
+				return skiff.NewInvalidTargetPathError(svcName, levelPath, fmt.Sprintf("level %s: %s", level.Name, reason))
+			}
+		}
+
+		levelContext := types.TemplateContext{
+			config.RegionKey: s.Region,
+			config.GroupKey:  s.ResolvedType.Group,
+			config.ScopeKey:  s.Scope,
+		}
+		maps.Copy(levelContext, metadata)
+		levelContext[config.LevelKey] = level.Name
+
+		levels = append(levels, ResolvedLevel{
+			Name:     level.Name,
+			Path:     levelPath,
+			Template: level.Template,
+			File:     level.FileName(),
+			Context:  levelContext,
+		})
+	}
+
+	s.ResolvedLevels = levels
+	return nil
+}
+
+// ResolveBackend renders the state key of the service from the backend in the
+// config. The key pattern has the strategy variables plus path, the resolved
+// target path, so ResolveTargetPath must run first. Services are left without
+// a backend when none is configured.
+func (s *Service) ResolveBackend(
+	ctx context.Context,
+	svcName string,
+	metadata types.Metadata,
+) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.Backend.IsSet() {
+		s.ResolvedBackend = nil
+		return nil
+	}
+
+	strategyContext := s.buildStrategyContext(svcName, metadata)
+	strategyContext[config.PathKey] = s.ResolvedTargetPath
+
+	key, err := renderPath(cfg.Backend.KeyTemplate(), strategyContext)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the state key of %s: %w", svcName, err)
+	}
+
+	if reason := utils.ValidatePath(key); reason != "" {
+		return fmt.Errorf("invalid state key %q for service %s: %s", key, svcName, reason)
+	}
+
+	backend := *cfg.Backend
+	backend.Key = key
+	backend.Config = maps.Clone(cfg.Backend.Config)
+	s.ResolvedBackend = &backend
+	return nil
+}
+
+// remoteState returns the terragrunt remote_state attributes of a resolved
+// backend.
+func remoteState(backend *config.Backend) map[string]any {
+	settings := map[string]any{}
+	maps.Copy(settings, backend.Config)
+	settings[backend.KeyAttribute()] = backend.Key
+
+	state := map[string]any{
+		"backend": backend.Type,
+		"config":  settings,
+	}
+
+	if backend.Generate != "" {
+		state["generate"] = map[string]any{
+			"path":      backend.Generate,
+			"if_exists": "overwrite_terragrunt",
+		}
+	}
+	return state
+}
+
+// renderPath renders a path template of the strategy and sanitizes the
+// result.
+func renderPath(text string, strategyContext types.StrategyContext) (string, error) {
+	resolvedPath, err := renderString(text, strategyContext)
+	if err != nil {
+		return "", err
+	}
+	return utils.SanitizePath(resolvedPath), nil
+}
+
+// renderString renders a template with the strategy variables available
+// through var.
+func renderString(text string, strategyContext types.StrategyContext) (string, error) {
 	tmpl, err := template.New("").
 		Option("missingkey=error").
 		Funcs(sprig.FuncMap()).
 		Funcs(template.FuncMap{config.VarKey: func() types.StrategyContext { return strategyContext }}).
-		Parse(cfg.Strategy.Template)
+		Parse(text)
 	if err != nil {
-		return err
+		return "", err
 	}
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, nil); err != nil {
-		return err
+		return "", err
 	}
-	resolvedPath, err := validatePath(&buf)
-	s.ResolvedTargetPath = utils.SanitizePath(resolvedPath)
-	return err
+	return validatePath(&buf)
 }
 
 func validatePath(buffer *bytes.Buffer) (string, error) {
@@ -247,9 +512,14 @@ func (s *Service) ResolveDependencies(
 			continue
 		}
 
+		// the type has to be resolved before reconciling, which reads its version
+		if _, err := targetSvc.ResolveType(ctx); err != nil {
+			continue
+		}
 		targetSvc.Reconcile(metadata)
-		targetSvc.ResolveType(ctx)
-		targetSvc.ResolveTargetPath(ctx, depName, metadata)
+		if err := targetSvc.ResolveTargetPath(ctx, depName, metadata); err != nil {
+			continue
+		}
 
 		relPath, err := filepath.Rel(s.ResolvedTargetPath, targetSvc.ResolvedTargetPath)
 		if err != nil {
@@ -265,10 +535,8 @@ func (s *Service) ResolveDependencies(
 			resolvedDep[k] = v
 		}
 
-		fmt.Println(targetSvc.ResolvedType)
-
 		for _, output := range targetSvc.ResolvedType.Outputs {
-			s.Inputs[output] = fmt.Sprintf("__dependency.%s.%s", depName, output)
+			s.Inputs[output] = OutputExpression(depName, output)
 		}
 
 		resolvedDependencies = append(resolvedDependencies, resolvedDep)
@@ -276,6 +544,22 @@ func (s *Service) ResolveDependencies(
 	s.Dependencies = resolvedDependencies
 }
 
+// OutputExpression returns the terragrunt expression reading the output key of
+// the dependency named dependency.
+func OutputExpression(dependency, key string) types.Expression {
+	return types.Expression(fmt.Sprintf("dependency.%s.%s.%s", dependency, config.OutputsKey, key))
+}
+
+// UnmarshalYAML decodes a dependency, keeping its expressions.
+func (d *Dependency) UnmarshalYAML(node *yaml.Node) error {
+	var values types.Values
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*d = Dependency(values)
+	return nil
+}
+
 func DefaultService(name, serviceType string) *Service {
 	return &Service{
 		Type:    serviceType,
@@ -334,7 +618,7 @@ func AddServiceType(ctx context.Context, serviceTypeName string, values string)
 		return err
 	}
 
-	oldCatalog, err = utils.ToYAML(svcCatalog)
+	oldCatalog, err = svcCatalog.Bytes()
 	if err != nil {
 		return err
 	}
@@ -373,7 +657,7 @@ func AddServiceType(ctx context.Context, serviceTypeName string, values string)
 
 	svcCatalog.AddServiceType(serviceTypeName, svc, false)
 
-	newCatalog, err = utils.ToYAML(svcCatalog)
+	newCatalog, err = svcCatalog.Bytes()
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
 	"github.com/nyambati/skiff/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -312,4 +313,200 @@ func TestResolveTargetPath(t *testing.T) {
 
 		assert.Equal(t, "123456/regions/us-west-2/web-service", service.ResolvedTargetPath)
 	})
+
+	t.Run("Reject Path Traversal From Labels", func(t *testing.T) {
+		setupTestConfig(t)
+
+		service := &Service{
+			Type:   "web",
+			Region: "us-west-2",
+			ResolvedType: &ServiceType{
+				Group: "default",
+			},
+		}
+
+		metadata := types.Metadata{
+			"id":          "../..",
+			"environment": "production",
+		}
+
+		ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+		err := service.ResolveTargetPath(ctx, "web-service", metadata)
+		require.Error(t, err)
+
+		var pathErr *skiff.InvalidTargetPathError
+		require.ErrorAs(t, err, &pathErr)
+		assert.Equal(t, "web-service", pathErr.Service)
+		assert.Equal(t, "../../regions/us-west-2/web-service", pathErr.Path)
+		assert.Empty(t, service.ResolvedTargetPath)
+	})
+}
+
+func TestResolveLevels(t *testing.T) {
+	setupTestConfig(t)
+	skiffConfig.Strategy.Levels = []config.Level{
+		{Name: "root", Template: "root.hcl.tmpl"},
+		{Name: "account", Path: "{{ var.id }}", Template: "account.hcl.tmpl"},
+		{Name: "region", Path: "{{ var.id }}/regions/{{ var.region }}", Template: "region.hcl.tmpl", File: "region.hcl"},
+	}
+
+	service := &Service{
+		Type:   "web",
+		Region: "us-west-2",
+		ResolvedType: &ServiceType{
+			Group: "default",
+		},
+	}
+
+	metadata := types.Metadata{"id": "123456"}
+	ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+	require.NoError(t, service.ResolveTargetPath(ctx, "web-service", metadata))
+	require.NoError(t, service.ResolveLevels(ctx, "web-service", metadata))
+
+	assert.Equal(t, []ResolvedLevel{
+		{
+			Name:     "root",
+			Path:     "",
+			Template: "root.hcl.tmpl",
+			File:     "root.hcl",
+			Context:  types.TemplateContext{"id": "123456", "region": "us-west-2", "group": "default", "scope": "", "level": "root"},
+		},
+		{
+			Name:     "account",
+			Path:     "123456",
+			Template: "account.hcl.tmpl",
+			File:     "account.hcl",
+			Context:  types.TemplateContext{"id": "123456", "region": "us-west-2", "group": "default", "scope": "", "level": "account"},
+		},
+		{
+			Name:     "region",
+			Path:     "123456/regions/us-west-2",
+			Template: "region.hcl.tmpl",
+			File:     "region.hcl",
+			Context:  types.TemplateContext{"id": "123456", "region": "us-west-2", "group": "default", "scope": "", "level": "region"},
+		},
+	}, service.ResolvedLevels)
+
+	require.NoError(t, service.BuildTemplateContext("web-service", metadata))
+	assert.Equal(t, map[string]any{
+		"root":    "../../../../root.hcl",
+		"account": "../../../account.hcl",
+		"region":  "../region.hcl",
+	}, service.TemplateContext[config.LevelsKey])
+
+	t.Run("Reject Level Outside Output Folder", func(t *testing.T) {
+		skiffConfig.Strategy.Levels = []config.Level{{Name: "up", Path: "..", Template: "up.tmpl"}}
+
+		err := service.ResolveLevels(ctx, "web-service", metadata)
+		assert.EqualError(t, err, `invalid target path ".." for service web-service: level up: segment ".." escapes the output folder`)
+	})
+}
+
+func TestResolveBackend(t *testing.T) {
+	setupTestConfig(t)
+	skiffConfig.Backend = &config.Backend{
+		Type:     config.BackendS3,
+		Generate: "backend.tf",
+		Config:   map[string]any{"bucket": "state", "region": "us-east-1"},
+	}
+	t.Cleanup(func() { skiffConfig.Backend = nil })
+
+	service := &Service{
+		Type:         "web",
+		Region:       "us-west-2",
+		ResolvedType: &ServiceType{Group: "default"},
+	}
+	metadata := types.Metadata{"id": "123456"}
+	ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+	require.NoError(t, service.ResolveTargetPath(ctx, "web-service", metadata))
+	require.NoError(t, service.ResolveBackend(ctx, "web-service", metadata))
+	assert.Equal(t, "123456/regions/us-west-2/web-service/terraform.tfstate", service.ResolvedBackend.Key)
+
+	require.NoError(t, service.BuildTemplateContext("web-service", metadata))
+	assert.Equal(t, map[string]any{
+		"backend": "s3",
+		"generate": map[string]any{
+			"path":      "backend.tf",
+			"if_exists": "overwrite_terragrunt",
+		},
+		"config": map[string]any{
+			"bucket": "state",
+			"region": "us-east-1",
+			"key":    "123456/regions/us-west-2/web-service/terraform.tfstate",
+		},
+	}, service.TemplateContext[config.RemoteStateKey])
+
+	t.Run("Key pattern", func(t *testing.T) {
+		skiffConfig.Backend.Key = "{{ var.id }}/{{ var.group }}-{{ var.service }}.tfstate"
+
+		require.NoError(t, service.ResolveBackend(ctx, "web-service", metadata))
+		assert.Equal(t, "123456/default-web-service.tfstate", service.ResolvedBackend.Key)
+	})
+
+	t.Run("Key leaving the bucket prefix", func(t *testing.T) {
+		skiffConfig.Backend.Key = "../{{ var.service }}.tfstate"
+
+		err := service.ResolveBackend(ctx, "web-service", metadata)
+		assert.EqualError(t, err, `invalid state key "../web-service.tfstate" for service web-service: segment ".." escapes the output folder`)
+	})
+
+	t.Run("No backend", func(t *testing.T) {
+		skiffConfig.Backend = nil
+
+		require.NoError(t, service.ResolveBackend(ctx, "web-service", metadata))
+		assert.Nil(t, service.ResolvedBackend)
+	})
+}
+
+func TestCatalogWrite(t *testing.T) {
+	content := `apiVersion: v1
+types:
+  # networking modules
+  vpc:
+    source: github.com/terraform-aws-modules/terraform-aws-vpc
+    version: 5.0.0 # pinned
+  rds:
+    source: github.com/terraform-aws-modules/terraform-aws-rds
+    version: 6.0.0
+`
+	t.Run("Only the edited type changes", func(t *testing.T) {
+		tempDir := setupTestConfig(t)
+		createServiceTypesFile(t, tempDir, content)
+		path := filepath.Join(tempDir, config.CatalogFile)
+
+		catalog := NewCatalog()
+		require.NoError(t, catalog.Read(path))
+		require.NoError(t, catalog.AddServiceType("rds", &ServiceType{Version: "6.1.0"}, false))
+		require.NoError(t, catalog.Write(path, true))
+
+		written, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `apiVersion: v1
+types:
+  # networking modules
+  vpc:
+    source: github.com/terraform-aws-modules/terraform-aws-vpc
+    version: 5.0.0 # pinned
+  rds:
+    source: github.com/terraform-aws-modules/terraform-aws-rds
+    version: 6.1.0
+`, string(written))
+	})
+
+	t.Run("New type is appended", func(t *testing.T) {
+		tempDir := setupTestConfig(t)
+		createServiceTypesFile(t, tempDir, content)
+		path := filepath.Join(tempDir, config.CatalogFile)
+
+		catalog := NewCatalog()
+		require.NoError(t, catalog.Read(path))
+		require.NoError(t, catalog.AddServiceType("s3", &ServiceType{Version: "4.0.0"}, false))
+
+		data, err := catalog.Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, content+"  s3:\n    version: 4.0.0\n", string(data))
+	})
 }
@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/types"
+)
+
+// Merge returns the providers with the settings of overrides applied on top.
+// Source, version and blocks are replaced when set, config attributes and
+// aliases are merged key by key.
+func (p Providers) Merge(overrides Providers) Providers {
+	merged := Providers{}
+	for name, provider := range p {
+		merged[name] = provider.clone()
+	}
+
+	for name, override := range overrides {
+		provider, exists := merged[name]
+		if !exists {
+			merged[name] = override.clone()
+			continue
+		}
+
+		if override.Source != "" {
+			provider.Source = override.Source
+		}
+		if override.Version != "" {
+			provider.Version = override.Version
+		}
+		if len(override.Blocks) > 0 {
+			provider.Blocks = slices.Clone(override.Blocks)
+		}
+		if len(override.Config) > 0 {
+			if provider.Config == nil {
+				provider.Config = map[string]any{}
+			}
+			maps.Copy(provider.Config, override.Config)
+		}
+		for alias, settings := range override.Aliases {
+			if provider.Aliases == nil {
+				provider.Aliases = map[string]map[string]any{}
+			}
+			provider.Aliases[alias] = maps.Clone(settings)
+		}
+		merged[name] = provider
+	}
+	return merged
+}
+
+func (p Provider) clone() Provider {
+	clone := p
+	clone.Config = maps.Clone(p.Config)
+	clone.Blocks = slices.Clone(p.Blocks)
+	if p.Aliases != nil {
+		clone.Aliases = make(map[string]map[string]any, len(p.Aliases))
+		for alias, settings := range p.Aliases {
+			clone.Aliases[alias] = maps.Clone(settings)
+		}
+	}
+	return clone
+}
+
+// ToMap returns the provider in the shape the HCL renderer expects.
+func (p Provider) ToMap(name string) map[string]any {
+	source := p.Source
+	if source == "" {
+		source = "hashicorp/" + name
+	}
+
+	aliases := map[string]any{}
+	for alias, settings := range p.Aliases {
+		aliases[alias] = settings
+	}
+
+	blocks := make([]any, 0, len(p.Blocks))
+	for _, block := range p.Blocks {
+		blocks = append(blocks, block)
+	}
+
+	provider := map[string]any{
+		config.SourceKey:  source,
+		config.VersionKey: p.Version,
+		"config":          p.Config,
+		"aliases":         aliases,
+	}
+	if len(p.Blocks) > 0 {
+		provider["blocks"] = blocks
+	}
+	return provider
+}
+
+// ResolveProviders merges the providers of the manifest with the overrides of
+// the service and renders every string setting as a template with the same
+// variables as the strategy, so a provider can use {{ var.region }} or
+// {{ var.account_id }}.
+func (s *Service) ResolveProviders(svcName string, metadata types.Metadata, providers Providers) error {
+	merged := providers.Merge(s.Providers)
+	if len(merged) == 0 {
+		s.ResolvedProviders = nil
+		return nil
+	}
+
+	strategyContext := s.buildStrategyContext(svcName, metadata)
+
+	for name, provider := range merged {
+		settings, err := renderValues(provider.Config, strategyContext)
+		if err != nil {
+			return fmt.Errorf("failed to resolve provider %s of %s: %w", name, svcName, err)
+		}
+		provider.Config, _ = settings.(map[string]any)
+
+		for alias, settings := range provider.Aliases {
+			resolved, err := renderValues(settings, strategyContext)
+			if err != nil {
+				return fmt.Errorf("failed to resolve provider %s.%s of %s: %w", name, alias, svcName, err)
+			}
+			provider.Aliases[alias], _ = resolved.(map[string]any)
+		}
+		merged[name] = provider
+	}
+
+	s.ResolvedProviders = merged
+	return nil
+}
+
+// renderValues renders the templated strings found in value, descending into
+// maps and lists.
+func renderValues(value any, strategyContext types.StrategyContext) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		return renderString(v, strategyContext)
+
+	case map[string]any:
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			rendered, err := renderValues(item, strategyContext)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = rendered
+		}
+		return out, nil
+
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			rendered, err := renderValues(item, strategyContext)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rendered)
+		}
+		return out, nil
+
+	default:
+		return value, nil
+	}
+}
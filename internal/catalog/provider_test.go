@@ -0,0 +1,78 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveProviders(t *testing.T) {
+	manifestProviders := Providers{
+		"aws": {
+			Version: "~> 5.0",
+			Config: map[string]any{
+				"region": "{{ var.region }}",
+				"assume_role": map[string]any{
+					"role_arn": "arn:aws:iam::{{ var.account_id }}:role/deploy",
+				},
+			},
+		},
+	}
+
+	t.Run("Manifest providers are rendered per service", func(t *testing.T) {
+		service := &Service{Region: "eu-west-1", ResolvedType: &ServiceType{}}
+
+		require.NoError(t, service.ResolveProviders("vpc", types.Metadata{"account_id": "123"}, manifestProviders))
+		assert.Equal(t, Providers{
+			"aws": {
+				Version: "~> 5.0",
+				Config: map[string]any{
+					"region": "eu-west-1",
+					"assume_role": map[string]any{
+						"role_arn": "arn:aws:iam::123:role/deploy",
+					},
+				},
+			},
+		}, service.ResolvedProviders)
+
+		// the manifest settings keep their templates
+		assert.Equal(t, "{{ var.region }}", manifestProviders["aws"].Config["region"])
+	})
+
+	t.Run("Service overrides and aliases", func(t *testing.T) {
+		service := &Service{
+			Region:       "eu-west-1",
+			ResolvedType: &ServiceType{},
+			Providers: Providers{
+				"aws": {
+					Version: "~> 5.40",
+					Aliases: map[string]map[string]any{
+						"us_east_1": {"region": "us-east-1"},
+					},
+				},
+				"random": {},
+			},
+		}
+
+		require.NoError(t, service.ResolveProviders("cdn", types.Metadata{"account_id": "123"}, manifestProviders))
+		aws := service.ResolvedProviders["aws"]
+		assert.Equal(t, "~> 5.40", aws.Version)
+		assert.Equal(t, "eu-west-1", aws.Config["region"])
+		assert.Equal(t, map[string]map[string]any{"us_east_1": {"region": "us-east-1"}}, aws.Aliases)
+
+		require.NoError(t, service.BuildTemplateContext("cdn", types.Metadata{}))
+		body := service.TemplateContext[config.BodyKey].(map[string]interface{})
+		providers := body[config.ProvidersKey].(map[string]interface{})
+		assert.Equal(t, "hashicorp/random", providers["random"].(map[string]any)[config.SourceKey])
+	})
+
+	t.Run("No providers", func(t *testing.T) {
+		service := &Service{ResolvedType: &ServiceType{}}
+
+		require.NoError(t, service.ResolveProviders("vpc", types.Metadata{}, nil))
+		assert.Nil(t, service.ResolvedProviders)
+	})
+}
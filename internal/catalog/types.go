@@ -1,6 +1,9 @@
 package catalog
 
-import "github.com/nyambati/skiff/internal/types"
+import (
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/types"
+)
 
 type (
 	ServiceType struct {
@@ -9,6 +12,11 @@ type (
 		Version  string   `yaml:"version,omitempty"`
 		Template string   `yaml:"template,omitempty"`
 		Outputs  []string `yaml:"outputs,omitempty"`
+		// Protected services of the type are not destroyed by run without
+		// --force-protected.
+		Protected bool `yaml:"protected,omitempty"`
+		// Run overrides the run settings of .skiff for services of the type.
+		Run *config.RunSettings `yaml:"run,omitempty"`
 	}
 
 	Dependency map[string]any
@@ -20,17 +28,49 @@ type (
 		Region               string                `yaml:"region,omitempty"`
 		Scope                string                `yaml:"scope,omitempty"`
 		Version              string                `yaml:"version,omitempty"`
-		Inputs               map[string]any        `yaml:"inputs,omitempty"`
+		Inputs               types.Values          `yaml:"inputs,omitempty"`
 		Labels               map[string]any        `yaml:"labels,omitempty"`
 		Dependencies         []Dependency          `yaml:"dependencies,omitempty"`
+		Providers            Providers             `yaml:"providers,omitempty"`
+		Protected            bool                  `yaml:"protected,omitempty"`
 		ResolvedDependencies []Dependency          `yaml:"-"`
 		ResolvedType         *ServiceType          `yaml:"-"`
 		TemplateContext      types.TemplateContext `yaml:"-"`
 		ResolvedTargetPath   string                `yaml:"-"`
+		ResolvedLevels       []ResolvedLevel       `yaml:"-"`
+		ResolvedBackend      *config.Backend       `yaml:"-"`
+		ResolvedProviders    Providers             `yaml:"-"`
+	}
+
+	// Provider configures a terraform provider generated for the services.
+	// Config holds the provider block attributes, Blocks names the Config
+	// entries written as nested blocks instead of attributes and Aliases adds
+	// aliased copies of the provider with their own settings, for example
+	// another region.
+	Provider struct {
+		Source  string                    `yaml:"source,omitempty"`
+		Version string                    `yaml:"version,omitempty"`
+		Config  map[string]any            `yaml:"config,omitempty"`
+		Blocks  []string                  `yaml:"blocks,omitempty"`
+		Aliases map[string]map[string]any `yaml:"aliases,omitempty"`
+	}
+
+	// Providers maps provider names, such as aws, to their settings.
+	Providers map[string]Provider
+
+	// ResolvedLevel is a strategy level resolved for a service: the shared file
+	// File rendered from Template into the folder Path.
+	ResolvedLevel struct {
+		Name     string
+		Path     string
+		Template string
+		File     string
+		Context  types.TemplateContext
 	}
 
 	Catalog struct {
 		APIVersion string                 `yaml:"apiVersion,omitempty"`
 		Types      map[string]ServiceType `yaml:"types"`
+		raw        []byte                 `yaml:"-"`
 	}
 )
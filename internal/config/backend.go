@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Supported backend types.
+const (
+	BackendS3      = "s3"
+	BackendGCS     = "gcs"
+	BackendAzureRM = "azurerm"
+	BackendLocal   = "local"
+)
+
+// backendSettings lists, for every backend type, the config attribute holding
+// the state key and the attributes that must be set.
+var backendSettings = map[string]struct {
+	key      string
+	required []string
+}{
+	BackendS3:      {key: "key", required: []string{"bucket"}},
+	BackendGCS:     {key: "prefix", required: []string{"bucket"}},
+	BackendAzureRM: {key: "key", required: []string{"storage_account_name", "container_name"}},
+	BackendLocal:   {key: "path"},
+}
+
+// IsSet reports whether a backend has been configured.
+func (b *Backend) IsSet() bool {
+	return b != nil && b.Type != ""
+}
+
+// Validate checks the backend type and the settings it requires.
+func (b *Backend) Validate() error {
+	settings, ok := backendSettings[b.Type]
+	if !ok {
+		return fmt.Errorf("unsupported backend type %q, expected one of s3, gcs, azurerm or local", b.Type)
+	}
+
+	if _, ok := b.Config[settings.key]; ok {
+		return fmt.Errorf("backend %s: set the state key with backend.key instead of config.%s", b.Type, settings.key)
+	}
+
+	for _, name := range settings.required {
+		if value, ok := b.Config[name]; !ok || fmt.Sprint(value) == "" {
+			return fmt.Errorf("backend %s: config.%s is required", b.Type, name)
+		}
+	}
+	return nil
+}
+
+// KeyAttribute returns the config attribute the backend stores the state key
+// in, for example prefix for gcs.
+func (b *Backend) KeyAttribute() string {
+	return backendSettings[b.Type].key
+}
+
+// KeyTemplate returns the state key pattern, by default the target path of the
+// service followed by terraform.tfstate, or the target path alone for gcs
+// whose key is a prefix.
+func (b *Backend) KeyTemplate() string {
+	if b.Key != "" {
+		return b.Key
+	}
+	if b.Type == BackendGCS {
+		return "{{ var.path }}"
+	}
+	return "{{ var.path }}/terraform.tfstate"
+}
+
+// Location identifies the storage the state keys live in, keys only need to
+// be unique within a location.
+func (b *Backend) Location() string {
+	var parts []string
+	for _, name := range backendSettings[b.Type].required {
+		parts = append(parts, fmt.Sprint(b.Config[name]))
+	}
+	return strings.Join(append([]string{b.Type}, parts...), "/")
+}
+
+// Merge returns the backend with the settings of override applied on top.
+// The config maps are merged key by key, a different type replaces the
+// config altogether.
+func (b *Backend) Merge(override *Backend) *Backend {
+	if override == nil {
+		return b.clone()
+	}
+
+	merged := b.clone()
+	if merged == nil || (override.Type != "" && override.Type != merged.Type) {
+		merged = &Backend{Type: override.Type}
+	}
+
+	if override.Key != "" {
+		merged.Key = override.Key
+	}
+	if override.Generate != "" {
+		merged.Generate = override.Generate
+	}
+	if len(override.Config) > 0 {
+		if merged.Config == nil {
+			merged.Config = map[string]any{}
+		}
+		maps.Copy(merged.Config, override.Config)
+	}
+	return merged
+}
+
+func (b *Backend) clone() *Backend {
+	if b == nil {
+		return nil
+	}
+	clone := *b
+	clone.Config = maps.Clone(b.Config)
+	return &clone
+}
+
+// WithBackend returns a context whose config uses the backend of the .skiff
+// file with override, usually taken from a manifest, applied on top. The
+// resulting backend is validated when one is configured.
+func WithBackend(ctx context.Context, override *Backend) (context.Context, error) {
+	cfg, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := cfg.Backend.Merge(override)
+	if backend.IsSet() {
+		if err := backend.Validate(); err != nil {
+			return nil, err
+		}
+	} else if backend != nil && (backend.Key != "" || len(backend.Config) > 0) {
+		return nil, fmt.Errorf("backend is configured without a type")
+	}
+
+	scoped := *cfg
+	scoped.Backend = backend
+	return context.WithValue(ctx, ContextKey, &scoped), nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		backend Backend
+		err     string
+	}{
+		{
+			name:    "Valid s3",
+			backend: Backend{Type: BackendS3, Config: map[string]any{"bucket": "state", "region": "us-east-1"}},
+		},
+		{
+			name:    "Valid local",
+			backend: Backend{Type: BackendLocal},
+		},
+		{
+			name:    "Unsupported type",
+			backend: Backend{Type: "consul"},
+			err:     `unsupported backend type "consul", expected one of s3, gcs, azurerm or local`,
+		},
+		{
+			name:    "Missing bucket",
+			backend: Backend{Type: BackendGCS},
+			err:     "backend gcs: config.bucket is required",
+		},
+		{
+			name:    "Missing container",
+			backend: Backend{Type: BackendAzureRM, Config: map[string]any{"storage_account_name": "state"}},
+			err:     "backend azurerm: config.container_name is required",
+		},
+		{
+			name:    "Key in config",
+			backend: Backend{Type: BackendS3, Config: map[string]any{"bucket": "state", "key": "fixed.tfstate"}},
+			err:     "backend s3: set the state key with backend.key instead of config.key",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.backend.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestBackendKey(t *testing.T) {
+	s3 := &Backend{Type: BackendS3, Config: map[string]any{"bucket": "state"}}
+	assert.Equal(t, "{{ var.path }}/terraform.tfstate", s3.KeyTemplate())
+	assert.Equal(t, "key", s3.KeyAttribute())
+	assert.Equal(t, "s3/state", s3.Location())
+
+	gcs := &Backend{Type: BackendGCS, Key: "{{ var.account_id }}/{{ var.service }}", Config: map[string]any{"bucket": "state"}}
+	assert.Equal(t, "{{ var.account_id }}/{{ var.service }}", gcs.KeyTemplate())
+	assert.Equal(t, "prefix", gcs.KeyAttribute())
+}
+
+func TestWithBackend(t *testing.T) {
+	cfg := &Config{
+		Backend: &Backend{
+			Type:     BackendS3,
+			Generate: "backend.tf",
+			Config:   map[string]any{"bucket": "state", "region": "us-east-1"},
+		},
+	}
+	ctx := context.WithValue(context.Background(), ContextKey, cfg)
+
+	t.Run("Manifest settings are merged", func(t *testing.T) {
+		scopedCtx, err := WithBackend(ctx, &Backend{Config: map[string]any{"bucket": "team-state"}})
+		require.NoError(t, err)
+
+		scoped, err := FromContext(scopedCtx)
+		require.NoError(t, err)
+		assert.Equal(t, &Backend{
+			Type:     BackendS3,
+			Generate: "backend.tf",
+			Config:   map[string]any{"bucket": "team-state", "region": "us-east-1"},
+		}, scoped.Backend)
+
+		// the .skiff backend is left untouched
+		assert.Equal(t, "state", cfg.Backend.Config["bucket"])
+	})
+
+	t.Run("Another type replaces the config", func(t *testing.T) {
+		scopedCtx, err := WithBackend(ctx, &Backend{Type: BackendLocal})
+		require.NoError(t, err)
+
+		scoped, err := FromContext(scopedCtx)
+		require.NoError(t, err)
+		assert.Equal(t, &Backend{Type: BackendLocal}, scoped.Backend)
+	})
+
+	t.Run("Invalid merged backend", func(t *testing.T) {
+		_, err := WithBackend(ctx, &Backend{Type: BackendGCS})
+		assert.EqualError(t, err, "backend gcs: config.bucket is required")
+	})
+
+	t.Run("No backend", func(t *testing.T) {
+		empty := context.WithValue(context.Background(), ContextKey, &Config{})
+		scopedCtx, err := WithBackend(empty, nil)
+		require.NoError(t, err)
+
+		scoped, err := FromContext(scopedCtx)
+		require.NoError(t, err)
+		assert.False(t, scoped.Backend.IsSet())
+	})
+}
@@ -43,6 +43,12 @@ var serviceTypesTemplate []byte
 //go:embed templates/skiff.yaml
 var skiffConfigTemplate []byte
 
+//go:embed strategies/account-region-service.yaml
+var accountRegionServiceStrategy []byte
+
+//go:embed strategies/group-first.yaml
+var groupFirstStrategy []byte
+
 func InitProject(path string, force bool) error {
 	config := []struct {
 		Folder   string
@@ -59,6 +65,16 @@ func InitProject(path string, force bool) error {
 			File:     TerragruntTemplateFile,
 			Template: terragruntDefaultTemplate,
 		},
+		{
+			Folder:   StrategiesFolder,
+			File:     "account-region-service.yaml",
+			Template: accountRegionServiceStrategy,
+		},
+		{
+			Folder:   StrategiesFolder,
+			File:     "group-first.yaml",
+			Template: groupFirstStrategy,
+		},
 		{
 			Folder:   ".",
 			File:     SkiffConfigFile,
@@ -96,6 +96,8 @@ func TestInitProject(t *testing.T) {
 			expectedFiles: []string{
 				"manifests/catalog.yaml",
 				"templates/terragrunt.default.tmpl",
+				"strategies/account-region-service.yaml",
+				"strategies/group-first.yaml",
 				".skiff",
 			},
 		},
@@ -4,7 +4,10 @@ const (
 	ToolName               = "skiff"
 	CatalogFile            = "catalog.yaml"
 	TerragruntTemplateFile = "terragrunt.default.tmpl"
+	TerragruntFile         = "terragrunt.hcl"
 	SkiffConfigFile        = ".skiff"
+	HistoryFile            = ".skiff_history.jsonl"
+	StrategiesFolder       = "strategies"
 	ScopeRegional          = "regional"
 	ScopeGlobal            = "global"
 	ServiceKey             = "service"
@@ -26,4 +29,17 @@ const (
 	VarKey                 = "var"
 	ConfigPathKey          = "config_path"
 	OutputsKey             = "outputs"
+	LevelKey               = "level"
+	LevelsKey              = "levels"
+	PathKey                = "path"
+	RemoteStateKey         = "remote_state"
+	ProvidersKey           = "providers"
+	HCLKey                 = "hcl"
+	DependencyFuncKey      = "dependency"
+	OutputKey              = "output"
+	RelPathKey             = "relpath"
+	ManifestKey            = "manifest"
+	CatalogKey             = "catalog"
+	IncludeTemplateKey     = "include_template"
+	RequiredKey            = "required"
 )
@@ -0,0 +1,47 @@
+package config
+
+import "slices"
+
+// DefaultRetryPatterns match the output of transient terragrunt failures:
+// state lock contention, provider downloads and API throttling.
+var DefaultRetryPatterns = []string{
+	`Error acquiring the state lock`,
+	`Failed to install provider`,
+	`could not query provider registry`,
+	`(?i)throttl`,
+	`(?i)rate exceeded`,
+	`TooManyRequests`,
+	`RequestLimitExceeded`,
+}
+
+// Merge returns the run settings with the settings of override applied on
+// top, field by field, or nil when neither is set.
+func (s *RunSettings) Merge(override *RunSettings) *RunSettings {
+	if s == nil && override == nil {
+		return nil
+	}
+
+	merged := &RunSettings{}
+	if s != nil {
+		*merged = *s
+		merged.RetryOn = slices.Clone(s.RetryOn)
+	}
+	if override == nil {
+		return merged
+	}
+
+	if override.Timeout != "" {
+		merged.Timeout = override.Timeout
+	}
+	if override.Retries != nil {
+		retries := *override.Retries
+		merged.Retries = &retries
+	}
+	if override.RetryDelay != "" {
+		merged.RetryDelay = override.RetryDelay
+	}
+	if len(override.RetryOn) > 0 {
+		merged.RetryOn = slices.Clone(override.RetryOn)
+	}
+	return merged
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSettingsMerge(t *testing.T) {
+	var unset *RunSettings
+	assert.Nil(t, unset.Merge(nil))
+
+	retries, none := 3, 0
+	global := &RunSettings{Timeout: "30m", Retries: &retries, RetryDelay: "10s", RetryOn: []string{"lock"}}
+
+	merged := global.Merge(&RunSettings{Timeout: "2h", Retries: &none})
+	assert.Equal(t, "2h", merged.Timeout)
+	assert.Equal(t, 0, *merged.Retries)
+	assert.Equal(t, "10s", merged.RetryDelay)
+	assert.Equal(t, []string{"lock"}, merged.RetryOn)
+	assert.Equal(t, 3, *global.Retries)
+
+	merged = unset.Merge(&RunSettings{RetryOn: []string{"throttl"}})
+	assert.Equal(t, &RunSettings{RetryOn: []string{"throttl"}}, merged)
+}
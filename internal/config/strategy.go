@@ -0,0 +1,184 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StrategiesPath returns the folder holding the named strategy files. It
+// defaults to a strategies folder next to the manifests folder.
+func (c *Config) StrategiesPath() string {
+	if c.Strategies != "" {
+		return c.Strategies
+	}
+	return filepath.Join(filepath.Dir(c.Manifests), StrategiesFolder)
+}
+
+// LoadStrategy reads the named strategy from the strategies folder.
+func (c *Config) LoadStrategy(name string) (*Strategy, error) {
+	path, err := c.StrategyFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadStrategyFile(path)
+}
+
+// ReadStrategyFile reads a strategy from a YAML file. The strategy is named
+// after the file.
+func ReadStrategyFile(path string) (*Strategy, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	buff, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var strategy Strategy
+	if err := yaml.Unmarshal(buff, &strategy); err != nil {
+		return nil, fmt.Errorf("failed to parse strategy %s: %w", name, err)
+	}
+
+	strategy.Name = name
+	if err := strategy.Validate(); err != nil {
+		return nil, err
+	}
+	return &strategy, nil
+}
+
+// Validate checks that the strategy defines a path template and that its
+// levels are named uniquely and have a template.
+func (s *Strategy) Validate() error {
+	name := s.Name
+	if name == "" {
+		name = SkiffConfigFile
+	}
+
+	if strings.TrimSpace(s.Template) == "" {
+		return fmt.Errorf("strategy %s does not define a template", name)
+	}
+
+	seen := map[string]bool{}
+	for i, level := range s.Levels {
+		switch {
+		case level.Name == "":
+			return fmt.Errorf("strategy %s: level %d has no name", name, i)
+		case seen[level.Name]:
+			return fmt.Errorf("strategy %s: level %s is defined twice", name, level.Name)
+		case level.Template == "":
+			return fmt.Errorf("strategy %s: level %s does not define a template", name, level.Name)
+		case strings.ContainsAny(level.FileName(), `/\`):
+			return fmt.Errorf("strategy %s: level %s file must be a file name, not a path", name, level.Name)
+		}
+		seen[level.Name] = true
+	}
+	return nil
+}
+
+// FileName returns the name of the file the level is written to, the level
+// name with an .hcl extension unless File is set.
+func (l Level) FileName() string {
+	if l.File != "" {
+		return l.File
+	}
+	return l.Name + ".hcl"
+}
+
+// ResolveStrategy returns the strategy to use for a manifest. A non-empty name,
+// usually taken from the manifest, selects a named strategy. Otherwise the
+// strategy of the .skiff file is used, either by its name or its inline
+// template.
+func (c *Config) ResolveStrategy(name string) (*Strategy, error) {
+	if name == "" {
+		name = c.Strategy.Name
+	}
+
+	if name == "" {
+		strategy := c.Strategy
+		if err := strategy.Validate(); err != nil {
+			return nil, err
+		}
+		return &strategy, nil
+	}
+
+	return c.LoadStrategy(name)
+}
+
+// StrategyNames returns the names of every strategy file in the strategies
+// folder, sorted alphabetically.
+func (c *Config) StrategyNames() ([]string, error) {
+	entries, err := os.ReadDir(c.StrategiesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read strategies directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ext))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// StrategyFile returns the path of the file defining the named strategy.
+func (c *Config) StrategyFile(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid strategy name %q", name)
+	}
+
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(c.StrategiesPath(), name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("strategy %s does not exist in %s", name, c.StrategiesPath())
+}
+
+// WithStrategy returns a context whose config uses the strategy selected by
+// name, see ResolveStrategy. Path resolution reads the strategy from the
+// config in the context, so this scopes a strategy to a single manifest.
+func WithStrategy(ctx context.Context, name string) (context.Context, error) {
+	cfg, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := cfg.ResolveStrategy(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return withStrategy(ctx, cfg, strategy), nil
+}
+
+// WithStrategyOverride returns a context whose config uses strategy, ignoring
+// the strategy selected by manifests or the .skiff file.
+func WithStrategyOverride(ctx context.Context, strategy *Strategy) (context.Context, error) {
+	cfg, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return withStrategy(ctx, cfg, strategy), nil
+}
+
+func withStrategy(ctx context.Context, cfg *Config, strategy *Strategy) context.Context {
+	scoped := *cfg
+	scoped.Strategy = *strategy
+	return context.WithValue(ctx, ContextKey, &scoped)
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStrategies(t *testing.T, files map[string]string) *Config {
+	tempDir := t.TempDir()
+	cfg := &Config{
+		Path: Path{
+			Manifests: filepath.Join(tempDir, "manifests"),
+		},
+		Strategy: Strategy{
+			Description: "inline",
+			Template:    "{{ var.service }}",
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(cfg.StrategiesPath(), 0755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.StrategiesPath(), name), []byte(content), 0644))
+	}
+	return cfg
+}
+
+func TestStrategiesPath(t *testing.T) {
+	cfg := &Config{Path: Path{Manifests: "skiff/manifests"}}
+	assert.Equal(t, "skiff/strategies", cfg.StrategiesPath())
+
+	cfg.Strategies = "layouts"
+	assert.Equal(t, "layouts", cfg.StrategiesPath())
+}
+
+func TestLoadStrategy(t *testing.T) {
+	cfg := setupStrategies(t, map[string]string{
+		"group-first.yaml": "description: group first\ntemplate: \"{{ var.group }}/{{ var.service }}\"\n",
+		"empty.yml":        "description: no template\n",
+		"README.md":        "not a strategy\n",
+	})
+
+	s, err := cfg.LoadStrategy("group-first")
+	require.NoError(t, err)
+	assert.Equal(t, "group-first", s.Name)
+	assert.Equal(t, "group first", s.Description)
+	assert.Equal(t, "{{ var.group }}/{{ var.service }}", s.Template)
+
+	_, err = cfg.LoadStrategy("empty")
+	assert.EqualError(t, err, "strategy empty does not define a template")
+
+	_, err = cfg.LoadStrategy("missing")
+	assert.ErrorContains(t, err, "strategy missing does not exist")
+
+	_, err = cfg.LoadStrategy("../manifests/catalog")
+	assert.ErrorContains(t, err, "invalid strategy name")
+
+	names, err := cfg.StrategyNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"empty", "group-first"}, names)
+}
+
+func TestResolveStrategy(t *testing.T) {
+	cfg := setupStrategies(t, map[string]string{
+		"group-first.yaml":            "template: \"{{ var.group }}/{{ var.service }}\"\n",
+		"account-region-service.yaml": "template: \"{{ var.account_id }}/{{ var.region }}/{{ var.service }}\"\n",
+	})
+
+	s, err := cfg.ResolveStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, "{{ var.service }}", s.Template, "inline strategy is used by default")
+
+	cfg.Strategy.Name = "account-region-service"
+	s, err = cfg.ResolveStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, "account-region-service", s.Name)
+
+	s, err = cfg.ResolveStrategy("group-first")
+	require.NoError(t, err)
+	assert.Equal(t, "group-first", s.Name, "manifest strategy takes precedence")
+}
+
+func TestWithStrategy(t *testing.T) {
+	cfg := setupStrategies(t, map[string]string{
+		"group-first.yaml": "template: \"{{ var.group }}/{{ var.service }}\"\n",
+	})
+	ctx := context.WithValue(context.Background(), ContextKey, cfg)
+
+	scopedCtx, err := WithStrategy(ctx, "group-first")
+	require.NoError(t, err)
+
+	scoped, err := FromContext(scopedCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "group-first", scoped.Strategy.Name)
+	assert.Equal(t, "{{ var.service }}", cfg.Strategy.Template, "original config is left untouched")
+}
+
+func TestStrategyLevels(t *testing.T) {
+	cfg := setupStrategies(t, map[string]string{
+		"layered.yaml": `template: "{{ var.account_id }}/{{ var.region }}/{{ var.service }}"
+levels:
+  - name: root
+    path: ""
+    template: root.hcl.tmpl
+  - name: account
+    path: "{{ var.account_id }}"
+    template: account.hcl.tmpl
+    file: account-vars.hcl
+`,
+	})
+
+	s, err := cfg.LoadStrategy("layered")
+	require.NoError(t, err)
+	require.Len(t, s.Levels, 2)
+	assert.Equal(t, "root.hcl", s.Levels[0].FileName())
+	assert.Equal(t, "account-vars.hcl", s.Levels[1].FileName())
+
+	testCases := []struct {
+		name   string
+		levels []Level
+		err    string
+	}{
+		{
+			name:   "Missing name",
+			levels: []Level{{Template: "root.hcl.tmpl"}},
+			err:    "strategy inline: level 0 has no name",
+		},
+		{
+			name:   "Duplicate name",
+			levels: []Level{{Name: "root", Template: "a.tmpl"}, {Name: "root", Template: "b.tmpl"}},
+			err:    "strategy inline: level root is defined twice",
+		},
+		{
+			name:   "Missing template",
+			levels: []Level{{Name: "account", Path: "{{ var.account_id }}"}},
+			err:    "strategy inline: level account does not define a template",
+		},
+		{
+			name:   "File with a path",
+			levels: []Level{{Name: "account", Template: "account.hcl.tmpl", File: "../account.hcl"}},
+			err:    "strategy inline: level account file must be a file name, not a path",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := Strategy{Name: "inline", Template: "{{ var.service }}", Levels: tc.levels}
+			assert.EqualError(t, strategy.Validate(), tc.err)
+		})
+	}
+}
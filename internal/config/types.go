@@ -9,15 +9,56 @@ type (
 	}
 
 	Strategy struct {
-		Description string `yaml:"description"`
-		Template    string `yaml:"template"`
+		Name        string  `json:"name,omitempty" yaml:"name,omitempty"`
+		Description string  `json:"description" yaml:"description"`
+		Template    string  `json:"template" yaml:"template"`
+		Levels      []Level `json:"levels,omitempty" yaml:"levels,omitempty"`
+	}
+
+	// Level is a shared file, such as a root terragrunt.hcl or an account.hcl,
+	// generated once in every folder its path template resolves to. Services
+	// pull it in with include or read_terragrunt_config.
+	Level struct {
+		Name     string `json:"name" yaml:"name"`
+		Path     string `json:"path" yaml:"path"`
+		Template string `json:"template" yaml:"template"`
+		File     string `json:"file,omitempty" yaml:"file,omitempty"`
+	}
+
+	// Backend configures the remote state of the generated services. Key is a
+	// template rendered with the strategy variables plus path, the target path
+	// of the service. Generate names the file terragrunt writes the backend
+	// block into, when empty the backend block is expected in the module.
+	Backend struct {
+		Type     string         `json:"type,omitempty" yaml:"type,omitempty"`
+		Key      string         `json:"key,omitempty" yaml:"key,omitempty"`
+		Generate string         `json:"generate,omitempty" yaml:"generate,omitempty"`
+		Config   map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
+	}
+
+	// RunSettings configures how run invokes terragrunt in a service folder.
+	// Timeout and RetryDelay are durations such as 30m, the delay doubles
+	// after every retry. A failed attempt is retried when its output matches
+	// one of the RetryOn regular expressions, DefaultRetryPatterns when empty.
+	RunSettings struct {
+		Timeout    string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+		Retries    *int     `json:"retries,omitempty" yaml:"retries,omitempty"`
+		RetryDelay string   `json:"retry_delay,omitempty" yaml:"retry_delay,omitempty" mapstructure:"retry_delay"`
+		RetryOn    []string `json:"retry_on,omitempty" yaml:"retry_on,omitempty" mapstructure:"retry_on"`
 	}
 
 	Config struct {
 		Version  string   `yaml:"version"`
 		Verbose  bool     `yaml:"verbose"`
 		Strategy Strategy `yaml:"strategy"`
-		Path     `yaml:"path"`
+		Backend  *Backend `yaml:"backend,omitempty"`
+		// Protected holds label selectors of services run refuses to destroy
+		// without --force-protected, like the services marked protected.
+		Protected []string `yaml:"protected,omitempty"`
+		// Run holds the run settings of every service, catalog types
+		// override them.
+		Run  *RunSettings `yaml:"run,omitempty"`
+		Path `yaml:"path"`
 	}
 
 	InitConfig struct {
@@ -1,6 +1,10 @@
 package skiff
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type ConfigurationError struct {
 	Message string
@@ -35,3 +39,177 @@ func (e *ServiceTypeDoesNotExistError) Error() string {
 func NewServiceTypeDoesNotExistError(serviceType string) *ServiceTypeDoesNotExistError {
 	return &ServiceTypeDoesNotExistError{Type: serviceType}
 }
+
+type ServiceNotFoundError struct {
+	Manifest string
+	Service  string
+}
+
+func (e *ServiceNotFoundError) Error() string {
+	return fmt.Sprintf("service %s does not exist in manifest %s", e.Service, e.Manifest)
+}
+
+func NewServiceNotFoundError(manifest, service string) *ServiceNotFoundError {
+	return &ServiceNotFoundError{Manifest: manifest, Service: service}
+}
+
+type InvalidTargetPathError struct {
+	Service string
+	Path    string
+	Reason  string
+}
+
+func (e *InvalidTargetPathError) Error() string {
+	return fmt.Sprintf("invalid target path %q for service %s: %s", e.Path, e.Service, e.Reason)
+}
+
+func NewInvalidTargetPathError(service, path, reason string) *InvalidTargetPathError {
+	return &InvalidTargetPathError{Service: service, Path: path, Reason: reason}
+}
+
+type TargetPathCollisionError struct {
+	Path     string
+	Services []string
+}
+
+func (e *TargetPathCollisionError) Error() string {
+	return fmt.Sprintf(
+		"services %s resolve to the same target path %s, adjust the strategy or the service labels",
+		strings.Join(e.Services, " and "), e.Path,
+	)
+}
+
+func NewTargetPathCollisionError(path string, services ...string) *TargetPathCollisionError {
+	return &TargetPathCollisionError{Path: path, Services: services}
+}
+
+type StateKeyCollisionError struct {
+	Key      string
+	Location string
+	Services []string
+}
+
+func (e *StateKeyCollisionError) Error() string {
+	return fmt.Sprintf(
+		"services %s share the state key %s in %s, adjust the backend key pattern",
+		strings.Join(e.Services, " and "), e.Key, e.Location,
+	)
+}
+
+func NewStateKeyCollisionError(key, location string, services ...string) *StateKeyCollisionError {
+	return &StateKeyCollisionError{Key: key, Location: location, Services: services}
+}
+
+// InvalidValueError is a value that cannot be written to the generated
+// configuration, Key is its path such as inputs.subnets[1].
+type InvalidValueError struct {
+	Key    string
+	Reason string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Reason)
+}
+
+func NewInvalidValueError(key, reason string) *InvalidValueError {
+	return &InvalidValueError{Key: key, Reason: reason}
+}
+
+// InvalidHCLError is a rendered file that does not parse as HCL. Line and
+// Column locate the first error, Snippet is the source line they point at.
+type InvalidHCLError struct {
+	File    string
+	Line    int
+	Column  int
+	Snippet string
+	Message string
+}
+
+func (e *InvalidHCLError) Error() string {
+	return fmt.Sprintf("%s:%d,%d: invalid HCL: %s\n  %d | %s", e.File, e.Line, e.Column, e.Message, e.Line, e.Snippet)
+}
+
+func NewInvalidHCLError(file string, line, column int, snippet, message string) *InvalidHCLError {
+	return &InvalidHCLError{File: file, Line: line, Column: column, Snippet: snippet, Message: message}
+}
+
+// RenderError is a failure to render a single generated file. Level is set
+// for the files of strategy levels, which belong to every service below them,
+// Service and Manifest then name the first of those services.
+type RenderError struct {
+	Manifest string
+	Service  string
+	Level    string
+	Template string
+	Output   string
+	Err      error
+}
+
+func (e *RenderError) Error() string {
+	owner := fmt.Sprintf("service %s/%s", e.Manifest, e.Service)
+	if e.Level != "" {
+		owner = fmt.Sprintf("level %s of %s", e.Level, owner)
+	}
+	return fmt.Sprintf("failed to render %s for %s with template %s: %v", e.Output, owner, e.Template, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// Key returns the path of the value that failed to render, if any.
+func (e *RenderError) Key() string {
+	var invalid *InvalidValueError
+	if errors.As(e.Err, &invalid) {
+		return invalid.Key
+	}
+	return ""
+}
+
+func NewRenderError(manifest, service, level, template, output string, err error) *RenderError {
+	return &RenderError{Manifest: manifest, Service: service, Level: level, Template: template, Output: output, Err: err}
+}
+
+// DestroyNotAllowedError is a run that would destroy resources of Services
+// without --allow-destroy.
+type DestroyNotAllowedError struct {
+	Command  string
+	Services []string
+}
+
+func (e *DestroyNotAllowedError) Error() string {
+	return fmt.Sprintf("%s of %s deletes resources, rerun with --allow-destroy to allow it", e.Command, strings.Join(e.Services, ", "))
+}
+
+func NewDestroyNotAllowedError(command string, services ...string) *DestroyNotAllowedError {
+	return &DestroyNotAllowedError{Command: command, Services: services}
+}
+
+// ProtectedServiceError is a run that would destroy resources of protected
+// Services without --force-protected.
+type ProtectedServiceError struct {
+	Command  string
+	Services []string
+}
+
+func (e *ProtectedServiceError) Error() string {
+	return fmt.Sprintf("refusing to %s protected services %s, rerun with --force-protected to allow it", e.Command, strings.Join(e.Services, ", "))
+}
+
+func NewProtectedServiceError(command string, services ...string) *ProtectedServiceError {
+	return &ProtectedServiceError{Command: command, Services: services}
+}
+
+// ConfirmationError is a destroy the user did not confirm by typing the name
+// of Manifest.
+type ConfirmationError struct {
+	Manifest string
+}
+
+func (e *ConfirmationError) Error() string {
+	return fmt.Sprintf("confirmation did not match manifest %s, nothing was destroyed", e.Manifest)
+}
+
+func NewConfirmationError(manifest string) *ConfirmationError {
+	return &ConfirmationError{Manifest: manifest}
+}
@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/selector"
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+type (
+	// ManifestSummary is the listing view of a manifest.
+	ManifestSummary struct {
+		Name     string         `json:"name" yaml:"name"`
+		Services int            `json:"services" yaml:"services"`
+		Metadata types.Metadata `json:"metadata" yaml:"metadata"`
+	}
+
+	// ServiceSummary is the listing view of a resolved service.
+	ServiceSummary struct {
+		Manifest string         `json:"manifest" yaml:"manifest"`
+		Name     string         `json:"name" yaml:"name"`
+		Type     string         `json:"type" yaml:"type"`
+		Version  string         `json:"version" yaml:"version"`
+		Region   string         `json:"region" yaml:"region"`
+		Scope    string         `json:"scope" yaml:"scope"`
+		Path     string         `json:"path" yaml:"path"`
+		Labels   map[string]any `json:"labels" yaml:"labels"`
+	}
+)
+
+// ListManifests writes every manifest, or the one named by manifestID, with
+// its metadata. The labels selector is matched against the metadata.
+func ListManifests(ctx context.Context, w io.Writer, manifestID, labels, format string) error {
+	labelSelector, err := selector.Parse(labels)
+	if err != nil {
+		return err
+	}
+
+	manifests, err := ReadAll(ctx, manifestID)
+	if err != nil {
+		return err
+	}
+
+	summaries := make([]ManifestSummary, 0, len(manifests))
+	for _, m := range manifests {
+		if !labelSelector.Matches(m.Metadata) {
+			continue
+		}
+
+		summaries = append(summaries, ManifestSummary{
+			Name:     m.Name,
+			Services: len(m.Services),
+			Metadata: m.Metadata,
+		})
+	}
+
+	return utils.WriteOutput(w, format, summaries, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSERVICES\tMETADATA")
+		for _, s := range summaries {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Name, s.Services, formatLabels(s.Metadata))
+		}
+		return tw.Flush()
+	})
+}
+
+// ListServices writes every service of the selected manifests with its
+// resolved type, version, region, scope, labels and target path. Services are
+// filtered with the same labels selector used by generate.
+func ListServices(ctx context.Context, w io.Writer, manifestID, labels, format string) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	labelSelector, err := selector.Parse(labels)
+	if err != nil {
+		return err
+	}
+
+	manifests, err := ReadAll(ctx, manifestID)
+	if err != nil {
+		return err
+	}
+
+	summaries := []ServiceSummary{}
+	for _, m := range manifests {
+		if err := m.Resolve(ctx); err != nil {
+			return err
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			svc := m.Services[name]
+			if !labelSelector.Matches(svc.Labels) {
+				continue
+			}
+
+			summaries = append(summaries, ServiceSummary{
+				Manifest: m.Name,
+				Name:     name,
+				Type:     svc.Type,
+				Version:  svc.Version,
+				Region:   svc.Region,
+				Scope:    svc.Scope,
+				Path:     filepath.Join(cfg.Terragrunt, svc.ResolvedTargetPath),
+				Labels:   svc.Labels,
+			})
+		}
+	}
+
+	return utils.WriteOutput(w, format, summaries, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MANIFEST\tSERVICE\tTYPE\tVERSION\tREGION\tSCOPE\tPATH\tLABELS")
+		for _, s := range summaries {
+			fmt.Fprintf(
+				tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				s.Manifest, s.Name, s.Type, s.Version, s.Region, s.Scope, s.Path, formatLabels(s.Labels),
+			)
+		}
+		return tw.Flush()
+	})
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels[T ~map[string]any](labels T) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, labels[key]))
+	}
+	return strings.Join(pairs, ",")
+}
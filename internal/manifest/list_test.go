@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListServices(t *testing.T) {
+	ctx := setupServiceTest(t, map[string]string{
+		"workload": serviceTestManifest,
+		"shared": `apiVersion: v1
+metadata:
+  account_id: "222222222222"
+  env: shared
+services:
+  vpc:
+    type: vpc
+    region: eu-west-1
+    labels:
+      team: network
+`,
+	})
+
+	t.Run("JSON output is sorted by manifest and service", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, ListServices(ctx, &buf, "", "", utils.OutputJSON))
+
+		var services []ServiceSummary
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &services))
+		require.Len(t, services, 3)
+
+		assert.Equal(t, "shared", services[0].Manifest)
+		assert.Equal(t, "vpc", services[0].Name)
+		assert.Equal(t, "5.0.0", services[0].Version)
+		assert.Equal(t, filepath.Join(skiffConfig.Terragrunt, "222222222222/eu-west-1/vpc"), services[0].Path)
+		assert.Equal(t, "network", services[0].Labels["team"])
+
+		assert.Equal(t, []string{"eks", "vpc"}, []string{services[1].Name, services[2].Name})
+		assert.Nil(t, services[2].Labels["team"], "labels must not leak between services")
+	})
+
+	t.Run("Labels filter", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, ListServices(ctx, &buf, "", "team=network", utils.OutputTable))
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		assert.Contains(t, string(lines[0]), "MANIFEST")
+		assert.Contains(t, string(lines[1]), "shared")
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, ListServices(ctx, &buf, "", "", "xml"))
+	})
+}
+
+func TestListManifests(t *testing.T) {
+	ctx := setupServiceTest(t, map[string]string{
+		"workload": serviceTestManifest,
+		"shared":   "apiVersion: v1\nmetadata:\n  env: shared\n",
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, ListManifests(ctx, &buf, "", "env=shared", utils.OutputYAML))
+	assert.Equal(t, "- name: shared\n  services: 0\n  metadata:\n    env: shared\n    name: shared\n", buf.String())
+}
+
+func TestListServicesWithManifestStrategies(t *testing.T) {
+	ctx := setupServiceTest(t, map[string]string{
+		"workload": serviceTestManifest,
+		"shared": `apiVersion: v1
+strategy: group-first
+metadata:
+  account_id: "222222222222"
+services:
+  vpc:
+    type: vpc
+    region: eu-west-1
+`,
+	})
+
+	strategies := filepath.Join(filepath.Dir(skiffConfig.Manifests), "strategies")
+	require.NoError(t, os.MkdirAll(strategies, 0755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(strategies, "group-first.yaml"),
+		[]byte("template: \"shared/{{ var.service }}/{{ var.region }}\"\n"),
+		0644,
+	))
+
+	var buf bytes.Buffer
+	require.NoError(t, ListServices(ctx, &buf, "", "", utils.OutputJSON))
+
+	var services []ServiceSummary
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &services))
+	require.Len(t, services, 3)
+
+	assert.Equal(t, filepath.Join(skiffConfig.Terragrunt, "shared/vpc/eu-west-1"), services[0].Path)
+	assert.Equal(t, filepath.Join(skiffConfig.Terragrunt, "111111111111/us-east-1/vpc"), services[2].Path)
+}
@@ -1,10 +1,14 @@
 package manifest
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/nyambati/skiff/internal/catalog"
@@ -14,7 +18,7 @@ import (
 	"github.com/nyambati/skiff/internal/utils"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 func Read(ctx context.Context, manifestName string) (*Manifest, error) {
@@ -37,29 +41,256 @@ func Read(ctx context.Context, manifestName string) (*Manifest, error) {
 	return m, nil
 }
 
-func (m *Manifest) Write(force bool) error {
-	data, err := m.ToYAML()
+// ReadAll reads the manifest identified by manifestID, or every manifest in
+// the manifests folder when manifestID is empty. Manifests are returned sorted
+// by name and are not resolved.
+func ReadAll(ctx context.Context, manifestID string) ([]*Manifest, error) {
+	var manifests []*Manifest
+
+	cfg, err := config.FromContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	identifiers, err := getManifestIdetifiers(manifestID, cfg.Manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, identifier := range identifiers {
+		identifier = strings.TrimSuffix(identifier, filepath.Ext(identifier))
+
+		m, err := Read(ctx, identifier)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// ReadOthers reads and resolves every manifest in the manifests folder except
+// the one named manifestID, for checks spanning the whole project. A manifest
+// that cannot be read or resolved is skipped with a warning, it fails the
+// commands that select it instead.
+func ReadOthers(ctx context.Context, manifestID string) ([]*Manifest, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	identifiers, err := getManifestIdetifiers("", cfg.Manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*Manifest
+	for _, identifier := range identifiers {
+		identifier = strings.TrimSuffix(identifier, filepath.Ext(identifier))
+		if identifier == manifestID {
+			continue
+		}
+
+		m, err := Read(ctx, identifier)
+		if err == nil {
+			err = m.Resolve(ctx)
+		}
+		if err != nil {
+			log.Warnf("skipping manifest %s in the checks across manifests: %v\n", identifier, err)
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// getManifestIdetifiers reads the account manifest IDs from the manifests folder based on the
+// provided account ID. If an empty string is provided, it reads all account manifest IDs
+// in the folder. It returns a slice of strings containing the account IDs and an error
+// if any issues occur during processing.
+func getManifestIdetifiers(manifestName, manifestPath string) ([]string, error) {
+	if manifestName != "" {
+		return []string{manifestName}, nil
+	}
+
+	manifestDir, err := os.ReadDir(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests directory: %w", err)
+	}
+
+	var manifestIDs []string
+	for _, entry := range manifestDir {
+		if isValidIdentifier(entry) {
+			manifestIDs = append(manifestIDs, entry.Name())
+		}
+	}
+
+	if len(manifestIDs) == 0 {
+		return nil, fmt.Errorf("no account manifests found in %s", manifestPath)
+	}
+
+	return manifestIDs, nil
+}
+
+func isValidIdentifier(entry os.DirEntry) bool {
+	return !entry.IsDir() &&
+		entry.Name() != config.CatalogFile &&
+		strings.HasSuffix(entry.Name(), ".yaml")
+}
+
+func (m *Manifest) Write(force bool) error {
 	if utils.FileExists(m.filepath) && !force {
 		log.Infof("skipping, manifest %s already exists, use --force to overwrite\n", m.Name)
 		return nil
 	}
 
-	data = utils.PrependWatermark(string(data), config.ToolName)
-	fmt.Println("writing to ", m.filepath)
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("writing to %s\n", m.filepath)
 	if err := utils.WriteFile(m.filepath, data); err != nil {
 		return err
 	}
+	m.raw = data
 
 	log.Infof(" ✅ manifest %s has been updated successfully\n", m.Name)
 	return nil
 }
 
 func (m *Manifest) ToYAML() ([]byte, error) {
-	return yaml.Marshal(m)
+	var buff bytes.Buffer
+	encoder := yaml.NewEncoder(&buff)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+
+	if err := encoder.Encode(m); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// Bytes returns the manifest document with the in-memory changes applied to
+// the file it was read from. Only the metadata keys and services that differ
+// from the original are rewritten, so comments, key order and formatting of
+// everything else are preserved. Manifests that do not exist on disk yet are
+// fully marshalled with the skiff watermark.
+func (m *Manifest) Bytes() ([]byte, error) {
+	if len(m.raw) == 0 {
+		data, err := m.ToYAML()
+		if err != nil {
+			return nil, err
+		}
+		return utils.PrependWatermark(string(data), config.ToolName), nil
+	}
+
+	original := m.empty()
+	if err := original.decode(m.raw); err != nil {
+		return nil, err
+	}
+
+	doc := m.raw
+	var err error
+
+	if m.APIVersion != original.APIVersion {
+		if doc, err = utils.SetYAMLValue(doc, m.APIVersion, "apiVersion"); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Strategy != original.Strategy {
+		if m.Strategy == "" {
+			doc, err = utils.DeleteYAMLValue(doc, "strategy")
+		} else {
+			doc, err = utils.SetYAMLValue(doc, m.Strategy, "strategy")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !reflect.DeepEqual(m.Backend, original.Backend) {
+		if m.Backend == nil {
+			doc, err = utils.DeleteYAMLValue(doc, "backend")
+		} else {
+			doc, err = utils.SetYAMLValue(doc, m.Backend, "backend")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !reflect.DeepEqual(m.Providers, original.Providers) {
+		if len(m.Providers) == 0 {
+			doc, err = utils.DeleteYAMLValue(doc, "providers")
+		} else {
+			doc, err = utils.SetYAMLValue(doc, m.Providers, "providers")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range sortedKeys(original.Metadata, m.Metadata) {
+		doc, err = patchEntry(doc, original.Metadata, m.Metadata, key, "metadata")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range sortedKeys(original.Services, m.Services) {
+		doc, err = patchEntry(doc, original.Services, m.Services, name, "services")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// patchEntry rewrites section.key in doc when its value differs between the
+// original and current maps, and removes it when it is no longer present.
+func patchEntry[T any](doc []byte, original, current map[string]T, key, section string) ([]byte, error) {
+	newValue, exists := current[key]
+	oldValue, existed := original[key]
+
+	switch {
+	case !exists:
+		return utils.DeleteYAMLValue(doc, section, key)
+	case existed && reflect.DeepEqual(oldValue, newValue):
+		return doc, nil
+	default:
+		return utils.SetYAMLValue(doc, newValue, section, key)
+	}
+}
+
+// sortedKeys returns the union of the keys of both maps, existing keys first
+// so that new entries are appended in a stable order.
+func sortedKeys[T any](original, current map[string]T) []string {
+	keys := slices.Sorted(maps.Keys(original))
+	for _, key := range slices.Sorted(maps.Keys(current)) {
+		if _, ok := original[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// empty returns a manifest with the defaults applied by Read, used as the
+// decoding target for the original document.
+func (m *Manifest) empty() *Manifest {
+	return &Manifest{
+		APIVersion: "v1",
+		Name:       m.Name,
+		Metadata:   types.Metadata{config.NameKey: m.Name},
+		filepath:   m.filepath,
+	}
+}
+
+func (m *Manifest) decode(buff []byte) error {
+	return yaml.Unmarshal(buff, m)
 }
 
 func (m *Manifest) read() error {
@@ -72,13 +303,25 @@ func (m *Manifest) read() error {
 		return err
 	}
 
-	if err := yaml.Unmarshal(buff, &m); err != nil {
+	if err := m.decode(buff); err != nil {
 		return err
 	}
+	m.raw = buff
 	return nil
 }
 
+// Resolve resolves every service of the manifest using the strategy selected
+// by the manifest, falling back to the strategy of the .skiff file.
 func (m *Manifest) Resolve(ctx context.Context) error {
+	ctx, err := config.WithStrategy(ctx, m.Strategy)
+	if err != nil {
+		return err
+	}
+
+	if ctx, err = config.WithBackend(ctx, m.Backend); err != nil {
+		return fmt.Errorf("manifest %s: %w", m.Name, err)
+	}
+
 	for svcName, svc := range m.Services {
 		rSvc, err := svc.ResolveType(ctx)
 		if err != nil {
@@ -92,6 +335,18 @@ func (m *Manifest) Resolve(ctx context.Context) error {
 			return err
 		}
 
+		if err := rSvc.ResolveLevels(ctx, svcName, m.Metadata); err != nil {
+			return err
+		}
+
+		if err := rSvc.ResolveBackend(ctx, svcName, m.Metadata); err != nil {
+			return err
+		}
+
+		if err := rSvc.ResolveProviders(svcName, m.Metadata, m.Providers); err != nil {
+			return err
+		}
+
 		rSvc.ResolveDependencies(
 			ctx,
 			m.Name,
@@ -131,7 +386,7 @@ func (m *Manifest) GetService(name string) (*catalog.Service, bool) {
 	return &svc, exists
 }
 
-func EditManifest(ctx context.Context, name, metadata string) error {
+func EditManifest(ctx context.Context, name, metadata string, opts EditOptions) error {
 
 	manifest, err := Read(ctx, name)
 	if err != nil {
@@ -140,7 +395,7 @@ func EditManifest(ctx context.Context, name, metadata string) error {
 
 	manifestPath := manifest.filepath
 
-	oldManifest, err := utils.ToYAML(manifest)
+	oldManifest, err := manifest.Bytes()
 	if err != nil {
 		return err
 	}
@@ -154,7 +409,29 @@ func EditManifest(ctx context.Context, name, metadata string) error {
 
 	}
 
-	content, err := utils.ToYAML(manifest)
+	if opts.IsSet() {
+		if manifest.Metadata, err = opts.applyToMetadata(manifest.Metadata); err != nil {
+			return err
+		}
+
+		// only the metadata changed, a broken service must not block the edit
+		if err := validateMetadata(manifest.Metadata); err != nil {
+			return err
+		}
+
+		content, err := manifest.Bytes()
+		if err != nil {
+			return err
+		}
+
+		if !utils.ConfirmChanges(oldManifest, content, opts.Yes) {
+			return nil
+		}
+
+		return manifest.Write(true)
+	}
+
+	content, err := manifest.Bytes()
 	if err != nil {
 		return err
 	}
@@ -169,7 +446,10 @@ func EditManifest(ctx context.Context, name, metadata string) error {
 		return err
 	}
 
+	manifest.Name = name
 	manifest.filepath = manifestPath
+	// the edited document is written as is, keeping the user's formatting
+	manifest.raw = content
 
 	if !utils.ShouldWrite(oldManifest, content) {
 		return nil
@@ -178,7 +458,7 @@ func EditManifest(ctx context.Context, name, metadata string) error {
 	return manifest.Write(true)
 }
 
-func AddService(ctx context.Context, manifestName, serviceName string) error {
+func AddService(ctx context.Context, manifestName, serviceName string, opts EditOptions) error {
 	var svcCatalog catalog.Catalog
 
 	cfg, err := config.FromContext(ctx)
@@ -208,29 +488,188 @@ func AddService(ctx context.Context, manifestName, serviceName string) error {
 		return err
 	}
 
-	newContent, err := utils.EditFile(manifestFilePath, oldContent)
-	if err != nil {
-		return err
+	if opts.IsSet() {
+		svc, err = opts.applyToService(svc)
+		if err != nil {
+			return err
+		}
+
+		if err := validateService(&svcCatalog, serviceName, svc); err != nil {
+			return err
+		}
+
+		newContent, err := utils.ToYAML(svc)
+		if err != nil {
+			return err
+		}
+
+		if !utils.ConfirmChanges(oldContent, newContent, opts.Yes) {
+			return nil
+		}
+
+		// edits replace the service so that unset keys are dropped
+		manifest.SetService(serviceName, svc)
+	} else {
+		newContent, err := utils.EditFile(manifestFilePath, oldContent)
+		if err != nil {
+			return err
+		}
+
+		svc, err = utils.FromYAML[catalog.Service](newContent)
+		if err != nil {
+			return err
+		}
+
+		if _, exists := svcCatalog.GetServiceType(svc.Type); !exists {
+			return skiff.NewServiceTypeDoesNotExistError(svc.Type)
+		}
+
+		if !utils.ShouldWrite(oldContent, newContent) {
+			return nil
+		}
+
+		manifest.AddService(serviceName, svc)
 	}
 
-	svc, err = utils.FromYAML[catalog.Service](newContent)
-	if err != nil {
+	if err := manifest.Write(true); err != nil {
 		return err
 	}
+	logrus.Infof("✅ Service %s has been added to %s\n", serviceName, manifestFilePath)
+	return nil
+}
+
+// SetService stores svc under name, replacing any existing definition.
+func (m *Manifest) SetService(name string, svc *catalog.Service) {
+	if m.Services == nil {
+		m.Services = map[string]catalog.Service{}
+	}
+	m.Services[name] = *svc
+}
+
+// validateMetadata checks the metadata of a manifest. Metadata values are
+// plain data, they also render the target paths of the services.
+func validateMetadata(metadata types.Metadata) error {
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("metadata: keys cannot be empty")
+		}
+		if types.ContainsExpression(metadata[key]) {
+			return fmt.Errorf("metadata.%s: expressions are only supported in service values", key)
+		}
+	}
+	return nil
+}
+
+func validateService(svcCatalog *catalog.Catalog, name string, svc *catalog.Service) error {
+	if svc.Type == "" {
+		return fmt.Errorf("service %s: type is required", name)
+	}
 
 	if _, exists := svcCatalog.GetServiceType(svc.Type); !exists {
 		return skiff.NewServiceTypeDoesNotExistError(svc.Type)
 	}
 
-	if !utils.ShouldWrite(oldContent, newContent) {
-		return nil
+	if svc.Scope != "" && svc.Scope != config.ScopeRegional && svc.Scope != config.ScopeGlobal {
+		return fmt.Errorf(
+			"service %s: invalid scope %q, expected %s or %s",
+			name, svc.Scope, config.ScopeRegional, config.ScopeGlobal,
+		)
 	}
+	return nil
+}
 
-	manifest.AddService(serviceName, svc)
+// IsSet reports whether any non-interactive edit was requested.
+func (o EditOptions) IsSet() bool {
+	return len(o.Set) > 0 || len(o.SetJSON) > 0 || len(o.Unset) > 0 || o.FromFile != ""
+}
 
-	if err := manifest.Write(true); err != nil {
-		return err
+// apply runs --set, --set-json and --unset against data, in that order.
+func (o EditOptions) apply(data map[string]any) error {
+	for _, input := range o.Set {
+		path, value, err := utils.ParseSetFlag(input)
+		if err != nil {
+			return err
+		}
+		if err := utils.SetValueAtPath(data, path, value); err != nil {
+			return err
+		}
+	}
+
+	for _, input := range o.SetJSON {
+		path, value, err := utils.ParseSetJSONFlag(input)
+		if err != nil {
+			return err
+		}
+		if err := utils.SetValueAtPath(data, path, value); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range o.Unset {
+		if err := utils.UnsetValueAtPath(data, path); err != nil {
+			return err
+		}
 	}
-	logrus.Infof("✅ Service %s has been added to %s\n", serviceName, manifestFilePath)
 	return nil
 }
+
+// load returns the document to edit: the contents of --from-file when given,
+// otherwise current encoded as a map.
+func (o EditOptions) load(current any) (map[string]any, error) {
+	var (
+		buff []byte
+		err  error
+	)
+
+	if o.FromFile != "" {
+		buff, err = os.ReadFile(o.FromFile)
+	} else {
+		buff, err = utils.ToYAML(current)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{}
+	if err := yaml.Unmarshal(buff, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]any{}
+	}
+	return data, nil
+}
+
+func (o EditOptions) applyToService(svc *catalog.Service) (*catalog.Service, error) {
+	data, err := o.load(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.apply(data); err != nil {
+		return nil, err
+	}
+
+	buff, err := utils.ToYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := utils.FromYAMLStrict[catalog.Service](buff)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service definition: %w", err)
+	}
+	return edited, nil
+}
+
+func (o EditOptions) applyToMetadata(metadata types.Metadata) (types.Metadata, error) {
+	data, err := o.load(map[string]any(metadata))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.apply(data); err != nil {
+		return nil, err
+	}
+	return types.Metadata(data), nil
+}
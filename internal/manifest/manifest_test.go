@@ -170,3 +170,223 @@ types:
 		assert.NoError(t, err)
 	})
 }
+
+func TestManifestWrite(t *testing.T) {
+	content := `# managed by the platform team
+apiVersion: v1
+metadata:
+  account_id: "1234567890" # prod account
+  env: production
+services:
+  # shared network
+  vpc:
+    type: vpc
+    region: us-east-1
+  rds:
+    type: rds
+    region: us-east-1
+`
+	t.Run("Only the edited service changes", func(t *testing.T) {
+		manifestName := createTempManifestFile(t, content)
+		ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+		m, err := Read(ctx, manifestName)
+		require.NoError(t, err)
+
+		require.NoError(t, m.AddService("rds", &catalog.Service{Region: "eu-west-1"}))
+		require.NoError(t, m.Write(true))
+
+		written, err := os.ReadFile(m.filepath)
+		require.NoError(t, err)
+		assert.Equal(t, `# managed by the platform team
+apiVersion: v1
+metadata:
+  account_id: "1234567890" # prod account
+  env: production
+services:
+  # shared network
+  vpc:
+    type: vpc
+    region: us-east-1
+  rds:
+    type: rds
+    region: eu-west-1
+`, string(written))
+	})
+
+	t.Run("Unchanged manifest is written byte-for-byte", func(t *testing.T) {
+		manifestName := createTempManifestFile(t, content)
+		ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+		m, err := Read(ctx, manifestName)
+		require.NoError(t, err)
+
+		data, err := m.Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	})
+
+	t.Run("Backend is added without touching the rest", func(t *testing.T) {
+		manifestName := createTempManifestFile(t, content)
+		ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+		m, err := Read(ctx, manifestName)
+		require.NoError(t, err)
+
+		m.Backend = &config.Backend{Config: map[string]any{"bucket": "prod-state"}}
+		data, err := m.Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, content+"backend:\n  config:\n    bucket: prod-state\n", string(data))
+	})
+
+	t.Run("Metadata keys are added in place", func(t *testing.T) {
+		manifestName := createTempManifestFile(t, content)
+		ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+		m, err := Read(ctx, manifestName)
+		require.NoError(t, err)
+
+		m.Metadata["team"] = "platform"
+		data, err := m.Bytes()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "  env: production\n  team: platform\nservices:\n")
+		assert.Contains(t, string(data), `account_id: "1234567890" # prod account`)
+	})
+}
+
+func TestAddServiceWithEditOptions(t *testing.T) {
+	content := `apiVersion: v1
+metadata:
+  env: production
+services:
+  vpc:
+    type: vpc
+    region: us-east-1
+    labels:
+      team: network
+`
+	catalogContent := `apiVersion: v1
+types:
+  vpc:
+    version: 5.0.0
+`
+
+	setup := func(t *testing.T) (context.Context, string) {
+		manifestName := createTempManifestFile(t, content)
+		err := os.WriteFile(filepath.Join(skiffConfig.Manifests, config.CatalogFile), []byte(catalogContent), 0644)
+		require.NoError(t, err)
+		return context.WithValue(context.Background(), "config", skiffConfig), manifestName
+	}
+
+	t.Run("Set, set-json and unset", func(t *testing.T) {
+		ctx, manifestName := setup(t)
+
+		err := AddService(ctx, manifestName, "vpc", EditOptions{
+			Set:     []string{"inputs.cidr=10.0.0.0/16", "inputs.max_azs=3"},
+			SetJSON: []string{`inputs.subnets=["10.0.1.0/24","10.0.2.0/24"]`},
+			Unset:   []string{"labels.team"},
+			Yes:     true,
+		})
+		require.NoError(t, err)
+
+		m, err := Read(ctx, manifestName)
+		require.NoError(t, err)
+		svc := m.Services["vpc"]
+		assert.Equal(t, types.Values{
+			"cidr":    "10.0.0.0/16",
+			"max_azs": 3,
+			"subnets": []any{"10.0.1.0/24", "10.0.2.0/24"},
+		}, svc.Inputs)
+		assert.Empty(t, svc.Labels)
+	})
+
+	t.Run("From file", func(t *testing.T) {
+		ctx, manifestName := setup(t)
+
+		serviceFile := filepath.Join(t.TempDir(), "service.yaml")
+		err := os.WriteFile(serviceFile, []byte("type: vpc\nregion: eu-west-1\n"), 0644)
+		require.NoError(t, err)
+
+		err = AddService(ctx, manifestName, "edge", EditOptions{FromFile: serviceFile, Yes: true})
+		require.NoError(t, err)
+
+		m, err := Read(ctx, manifestName)
+		require.NoError(t, err)
+		assert.Equal(t, "eu-west-1", m.Services["edge"].Region)
+		assert.Equal(t, "us-east-1", m.Services["vpc"].Region)
+	})
+
+	t.Run("Unknown service type is rejected", func(t *testing.T) {
+		ctx, manifestName := setup(t)
+
+		err := AddService(ctx, manifestName, "vpc", EditOptions{Set: []string{"type=rds"}, Yes: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "service type rds does not exist")
+	})
+
+	t.Run("Unknown field is rejected", func(t *testing.T) {
+		ctx, manifestName := setup(t)
+
+		err := AddService(ctx, manifestName, "vpc", EditOptions{Set: []string{"input.cidr=10.0.0.0/16"}, Yes: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid service definition")
+	})
+}
+
+func TestEditManifestWithEditOptions(t *testing.T) {
+	manifestName := createTempManifestFile(t, "apiVersion: v1\nmetadata:\n  env: production # current\n  team: data\n")
+	err := os.WriteFile(filepath.Join(skiffConfig.Manifests, config.CatalogFile), []byte("types: {}\n"), 0644)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+	err = EditManifest(ctx, manifestName, "", EditOptions{
+		Set:   []string{"account_id=123456789012"},
+		Unset: []string{"team"},
+		Yes:   true,
+	})
+	require.NoError(t, err)
+
+	written, err := os.ReadFile(filepath.Join(skiffConfig.Manifests, manifestName+".yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1\nmetadata:\n  env: production # current\n  account_id: 123456789012\n", string(written))
+}
+
+func TestEditManifestIgnoresServices(t *testing.T) {
+	manifestName := createTempManifestFile(t, "apiVersion: v1\nmetadata:\n  env: production\nservices:\n  legacy:\n    type: removed\n")
+	err := os.WriteFile(filepath.Join(skiffConfig.Manifests, config.CatalogFile), []byte("types: {}\n"), 0644)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), "config", skiffConfig)
+
+	// the type of legacy is missing from the catalog, metadata edits still work
+	require.NoError(t, EditManifest(ctx, manifestName, "", EditOptions{Set: []string{"team=data"}, Yes: true}))
+
+	err = EditManifest(ctx, manifestName, "", EditOptions{Set: []string{"vpc_id=!expr dependency.vpc.outputs.id"}, Yes: true})
+	assert.EqualError(t, err, "metadata.vpc_id: expressions are only supported in service values")
+}
+
+func TestGetManifestIDs(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Create some test manifest files
+	testFiles := []string{
+		"account1.yaml",
+		"account2.yaml",
+		"catalog.yaml", // should be ignored
+	}
+
+	for _, filename := range testFiles {
+		fullPath := filepath.Join(tempDir, filename)
+		err := os.WriteFile(fullPath, []byte("test content"), 0644)
+		require.NoError(t, err)
+	}
+
+	// Test with empty accountID (should return all non-service-types files)
+	accountIDs, err := getManifestIdetifiers("", tempDir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"account1.yaml", "account2.yaml"}, accountIDs)
+
+	// Test with specific accountID
+	accountIDs, err = getManifestIdetifiers("account1.yaml", tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"account1.yaml"}, accountIDs)
+}
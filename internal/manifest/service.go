@@ -0,0 +1,360 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// RemoveService deletes a service from a manifest. Services that depend on it
+// block the removal unless force is set, in which case their dependency
+// references are dropped as well.
+func RemoveService(ctx context.Context, manifestName, serviceName string, force bool) error {
+	m, err := Read(ctx, manifestName)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := m.Services[serviceName]; !exists {
+		return skiff.NewServiceNotFoundError(manifestName, serviceName)
+	}
+
+	targetPath, err := m.TargetPath(ctx, serviceName)
+	if err != nil {
+		return err
+	}
+
+	if dependents := m.Dependents(serviceName); len(dependents) > 0 {
+		if !force {
+			return fmt.Errorf(
+				"service %s is a dependency of %s, use --force to remove it and its references",
+				serviceName, strings.Join(dependents, ", "),
+			)
+		}
+		m.RenameDependency(serviceName, "")
+	}
+
+	delete(m.Services, serviceName)
+
+	if err := m.Write(true); err != nil {
+		return err
+	}
+
+	logrus.Infof("✅ service %s has been removed from %s\n", serviceName, manifestName)
+	logrus.Infof(
+		"ℹ️  generated folder %s is no longer managed, run `terragrunt destroy` in it before deleting it if the infrastructure should go too\n",
+		targetPath,
+	)
+	return nil
+}
+
+// MoveService renames a service and/or moves it to another manifest, updating
+// the dependency references that point at it. When opts.MoveGenerated is set
+// the already generated folder is relocated to the new target path.
+func MoveService(ctx context.Context, manifestName, oldName, newName string, opts MoveOptions) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if newName == "" {
+		newName = oldName
+	}
+
+	source, err := Read(ctx, manifestName)
+	if err != nil {
+		return err
+	}
+
+	svc, exists := source.Services[oldName]
+	if !exists {
+		return skiff.NewServiceNotFoundError(manifestName, oldName)
+	}
+
+	target := source
+	if opts.ToManifest != "" && opts.ToManifest != manifestName {
+		if target, err = Read(ctx, opts.ToManifest); err != nil {
+			return err
+		}
+		if !utils.FileExists(target.filepath) {
+			return fmt.Errorf("manifest %s does not exist", opts.ToManifest)
+		}
+	}
+
+	if target == source && newName == oldName {
+		return fmt.Errorf("nothing to move, provide a new name or --to-manifest")
+	}
+
+	if _, exists := target.Services[newName]; exists {
+		return fmt.Errorf("service %s already exists in manifest %s", newName, target.Name)
+	}
+
+	oldPath, err := source.TargetPath(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	if target == source {
+		delete(source.Services, oldName)
+		source.SetService(newName, &svc)
+		source.RenameDependency(oldName, newName)
+	} else {
+		if err := checkCrossManifestMove(source, target, oldName, &svc, opts.Force); err != nil {
+			return err
+		}
+		delete(source.Services, oldName)
+		target.SetService(newName, &svc)
+	}
+
+	newPath, err := target.TargetPath(ctx, newName)
+	if err != nil {
+		return err
+	}
+
+	// the target is written first so a failed write never loses the service
+	if target != source {
+		if err := target.Write(true); err != nil {
+			return err
+		}
+	}
+
+	if err := source.Write(true); err != nil {
+		if target != source {
+			return fmt.Errorf("service %s was added to manifest %s but could not be removed from manifest %s, remove it by hand: %w", newName, target.Name, manifestName, err)
+		}
+		return err
+	}
+
+	logrus.Infof("✅ service %s/%s has been moved to %s/%s\n", manifestName, oldName, target.Name, newName)
+
+	if oldPath == newPath {
+		logrus.Infof("ℹ️  target folder %s is unchanged, no state migration is needed\n", newPath)
+		return nil
+	}
+
+	oldFolder := filepath.Join(cfg.Terragrunt, oldPath)
+	newFolder := filepath.Join(cfg.Terragrunt, newPath)
+
+	if opts.MoveGenerated {
+		if err := relocateFolder(oldFolder, newFolder); err != nil {
+			return err
+		}
+	}
+
+	printStateMoveGuidance(newName, oldFolder, newFolder, opts.MoveGenerated)
+	return nil
+}
+
+// checkCrossManifestMove makes sure a service can leave source for target.
+// Dependencies only resolve within a manifest, so dependencies of the service
+// missing from target are always refused, and services of source depending
+// on it are refused unless force is set, in which case their references are
+// dropped.
+func checkCrossManifestMove(source, target *Manifest, name string, svc *catalog.Service, force bool) error {
+	var missing []string
+	for _, dep := range svc.Dependencies {
+		depName, ok := dep[config.ServiceKey].(string)
+		if !ok {
+			continue
+		}
+		if _, exists := target.Services[depName]; !exists {
+			missing = append(missing, depName)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf(
+			"cannot move service %s: its dependencies %s do not exist in %s, move them first or remove them from the service",
+			name, strings.Join(missing, ", "), target.Name,
+		)
+	}
+
+	dependents := source.Dependents(name)
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	if !force {
+		return fmt.Errorf("cannot move service %s: %s in %s depend on it, use --force to move it anyway", name, strings.Join(dependents, ", "), source.Name)
+	}
+
+	logrus.Warnf("removing the dependencies of %s in %s on %s\n", strings.Join(dependents, ", "), source.Name, name)
+	source.RenameDependency(name, "")
+	return nil
+}
+
+// Dependents returns the names of the services that depend on name.
+func (m *Manifest) Dependents(name string) []string {
+	var dependents []string
+	for _, svcName := range slices.Sorted(maps.Keys(m.Services)) {
+		for _, dep := range m.Services[svcName].Dependencies {
+			if dep[config.ServiceKey] == name {
+				dependents = append(dependents, svcName)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// RenameDependency rewrites every dependency on oldName to point at newName.
+// An empty newName removes the dependency entries instead.
+func (m *Manifest) RenameDependency(oldName, newName string) {
+	for svcName, svc := range m.Services {
+		dependencies := make([]catalog.Dependency, 0, len(svc.Dependencies))
+		changed := false
+
+		for _, dep := range svc.Dependencies {
+			if dep[config.ServiceKey] != oldName {
+				dependencies = append(dependencies, dep)
+				continue
+			}
+
+			changed = true
+			if newName == "" {
+				continue
+			}
+
+			renamed := maps.Clone(dep)
+			renamed[config.ServiceKey] = newName
+			dependencies = append(dependencies, renamed)
+		}
+
+		if changed {
+			svc.Dependencies = dependencies
+			m.Services[svcName] = svc
+		}
+	}
+}
+
+// TargetPath resolves the folder, relative to the terragrunt output folder,
+// that the strategy assigns to the named service. The manifest itself is left
+// untouched.
+func (m *Manifest) TargetPath(ctx context.Context, name string) (string, error) {
+	return m.TargetPathFor(ctx, name, nil)
+}
+
+// TargetPathFor resolves the target path of the named service with strategy
+// instead of the one selected by the manifest. A nil strategy behaves like
+// TargetPath.
+func (m *Manifest) TargetPathFor(ctx context.Context, name string, strategy *config.Strategy) (string, error) {
+	resolved, _, err := m.resolveFor(ctx, name, strategy)
+	if err != nil {
+		return "", err
+	}
+	return resolved.ResolvedTargetPath, nil
+}
+
+// StateKeyFor resolves the state key of the named service with strategy, like
+// TargetPathFor, from the backend of the .skiff file and the manifest. The key
+// is empty when no backend is configured.
+func (m *Manifest) StateKeyFor(ctx context.Context, name string, strategy *config.Strategy) (string, error) {
+	resolved, ctx, err := m.resolveFor(ctx, name, strategy)
+	if err != nil {
+		return "", err
+	}
+
+	if ctx, err = config.WithBackend(ctx, m.Backend); err != nil {
+		return "", err
+	}
+
+	if err := resolved.ResolveBackend(ctx, name, m.Metadata); err != nil {
+		return "", err
+	}
+	if resolved.ResolvedBackend == nil {
+		return "", nil
+	}
+	return resolved.ResolvedBackend.Key, nil
+}
+
+// resolveFor resolves a copy of the named service up to its target path with
+// strategy, or the strategy of the manifest when nil, and returns it with the
+// context it was resolved in.
+func (m *Manifest) resolveFor(ctx context.Context, name string, strategy *config.Strategy) (*catalog.Service, context.Context, error) {
+	svc, exists := m.Services[name]
+	if !exists {
+		return nil, nil, skiff.NewServiceNotFoundError(m.Name, name)
+	}
+
+	// resolution mutates the service, work on a copy
+	data, err := utils.ToYAML(svc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved, err := utils.FromYAML[catalog.Service](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if strategy != nil {
+		ctx, err = config.WithStrategyOverride(ctx, strategy)
+	} else {
+		ctx, err = config.WithStrategy(ctx, m.Strategy)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := resolved.ResolveType(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	resolved.Reconcile(m.Metadata)
+
+	if err := resolved.ResolveTargetPath(ctx, name, m.Metadata); err != nil {
+		return nil, nil, err
+	}
+	return resolved, ctx, nil
+}
+
+func relocateFolder(oldFolder, newFolder string) error {
+	if !utils.FileExists(oldFolder) {
+		logrus.Infof("ℹ️  %s has not been generated yet, nothing to relocate\n", oldFolder)
+		return nil
+	}
+
+	if utils.FileExists(newFolder) {
+		return fmt.Errorf("cannot relocate %s: %s already exists", oldFolder, newFolder)
+	}
+
+	if err := utils.CreateDirectory(filepath.Dir(newFolder)); err != nil {
+		return err
+	}
+
+	if err := os.Rename(oldFolder, newFolder); err != nil {
+		return fmt.Errorf("failed to relocate %s to %s: %w", oldFolder, newFolder, err)
+	}
+
+	logrus.Infof("✅ relocated %s to %s\n", oldFolder, newFolder)
+	return nil
+}
+
+func printStateMoveGuidance(name, oldFolder, newFolder string, moved bool) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "\nℹ️  %s now renders to %s instead of %s.\n", name, newFolder, oldFolder)
+	if !moved {
+		fmt.Fprintf(&b, "   Move the generated folder (or rerun with --move-generated):\n\n")
+		fmt.Fprintf(&b, "     mv %s %s\n\n", oldFolder, newFolder)
+	}
+
+	fmt.Fprintf(&b, "   If the remote state key is derived from the folder (path_relative_to_include),\n")
+	fmt.Fprintf(&b, "   copy the state to the new key before the next apply:\n\n")
+	fmt.Fprintf(&b, "     terragrunt state pull --terragrunt-working-dir %s > %s.tfstate\n", oldFolder, name)
+	fmt.Fprintf(&b, "     terragrunt state push --terragrunt-working-dir %s %s.tfstate\n\n", newFolder, name)
+	fmt.Fprintf(&b, "   Resource addresses inside the state do not change, so no `terragrunt state mv`\n")
+	fmt.Fprintf(&b, "   or `moved` blocks are needed unless the module itself was changed.\n")
+
+	fmt.Fprintln(os.Stderr, b.String())
+}
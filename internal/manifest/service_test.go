@@ -0,0 +1,158 @@
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupServiceTest(t *testing.T, manifests map[string]string) context.Context {
+	tempDir := t.TempDir()
+	skiffConfig = &config.Config{
+		Path: config.Path{
+			Manifests:  filepath.Join(tempDir, "manifests"),
+			Terragrunt: filepath.Join(tempDir, "terragrunt"),
+		},
+		Strategy: config.Strategy{
+			Template: "{{ var.account_id }}/{{ var.region }}/{{ var.service }}",
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(skiffConfig.Manifests, 0755))
+	catalogContent := "apiVersion: v1\ntypes:\n  vpc:\n    version: 5.0.0\n  eks:\n    version: 20.0.0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(skiffConfig.Manifests, config.CatalogFile), []byte(catalogContent), 0644))
+
+	for name, content := range manifests {
+		require.NoError(t, os.WriteFile(filepath.Join(skiffConfig.Manifests, name+".yaml"), []byte(content), 0644))
+	}
+
+	return context.WithValue(context.Background(), "config", skiffConfig)
+}
+
+const serviceTestManifest = `apiVersion: v1
+metadata:
+  account_id: "111111111111"
+services:
+  vpc:
+    type: vpc
+    region: us-east-1
+  eks:
+    type: eks
+    region: us-east-1
+    dependencies:
+      - service: vpc
+`
+
+func TestRemoveService(t *testing.T) {
+	t.Run("Dependents block removal", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{"workload": serviceTestManifest})
+
+		err := RemoveService(ctx, "workload", "vpc", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "dependency of eks")
+	})
+
+	t.Run("Force removes references", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{"workload": serviceTestManifest})
+
+		require.NoError(t, RemoveService(ctx, "workload", "vpc", true))
+
+		m, err := Read(ctx, "workload")
+		require.NoError(t, err)
+		assert.NotContains(t, m.Services, "vpc")
+		assert.Empty(t, m.Services["eks"].Dependencies)
+	})
+
+	t.Run("Missing service", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{"workload": serviceTestManifest})
+
+		err := RemoveService(ctx, "workload", "rds", false)
+		require.Error(t, err)
+		assert.Equal(t, "service rds does not exist in manifest workload", err.Error())
+	})
+}
+
+func TestMoveService(t *testing.T) {
+	t.Run("Rename updates dependencies", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{"workload": serviceTestManifest})
+
+		require.NoError(t, MoveService(ctx, "workload", "vpc", "network", MoveOptions{}))
+
+		written, err := os.ReadFile(filepath.Join(skiffConfig.Manifests, "workload.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, `apiVersion: v1
+metadata:
+  account_id: "111111111111"
+services:
+  eks:
+    type: eks
+    region: us-east-1
+    dependencies:
+      - service: network
+  network:
+    type: vpc
+    region: us-east-1
+`, string(written))
+	})
+
+	t.Run("Rename relocates generated folder", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{"workload": serviceTestManifest})
+		oldFolder := filepath.Join(skiffConfig.Terragrunt, "111111111111/us-east-1/vpc")
+		require.NoError(t, os.MkdirAll(oldFolder, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(oldFolder, "terragrunt.hcl"), []byte("inputs = {}\n"), 0644))
+
+		require.NoError(t, MoveService(ctx, "workload", "vpc", "network", MoveOptions{MoveGenerated: true}))
+
+		assert.NoDirExists(t, oldFolder)
+		assert.FileExists(t, filepath.Join(skiffConfig.Terragrunt, "111111111111/us-east-1/network/terragrunt.hcl"))
+	})
+
+	t.Run("Move to another manifest", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{
+			"workload": serviceTestManifest,
+			"shared":   "apiVersion: v1\nmetadata:\n  account_id: \"222222222222\"\nservices: {}\n",
+		})
+
+		err := MoveService(ctx, "workload", "vpc", "", MoveOptions{ToManifest: "shared"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "eks in workload depend on it")
+
+		require.NoError(t, MoveService(ctx, "workload", "vpc", "", MoveOptions{ToManifest: "shared", Force: true}))
+
+		source, err := Read(ctx, "workload")
+		require.NoError(t, err)
+		assert.NotContains(t, source.Services, "vpc")
+		assert.Empty(t, source.Services["eks"].Dependencies)
+
+		target, err := Read(ctx, "shared")
+		require.NoError(t, err)
+		assert.Equal(t, "vpc", target.Services["vpc"].Type)
+	})
+
+	t.Run("Missing dependencies block a forced move", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{
+			"workload": serviceTestManifest,
+			"shared":   "apiVersion: v1\nmetadata:\n  account_id: \"222222222222\"\n",
+		})
+
+		err := MoveService(ctx, "workload", "eks", "", MoveOptions{ToManifest: "shared", Force: true})
+		assert.EqualError(t, err, "cannot move service eks: its dependencies vpc do not exist in shared, move them first or remove them from the service")
+
+		source, err := Read(ctx, "workload")
+		require.NoError(t, err)
+		assert.Contains(t, source.Services, "eks")
+	})
+
+	t.Run("Existing target name", func(t *testing.T) {
+		ctx := setupServiceTest(t, map[string]string{"workload": serviceTestManifest})
+
+		err := MoveService(ctx, "workload", "vpc", "eks", MoveOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "service eks already exists")
+	})
+}
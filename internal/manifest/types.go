@@ -2,6 +2,7 @@ package manifest
 
 import (
 	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
 	"github.com/nyambati/skiff/internal/types"
 )
 
@@ -9,8 +10,30 @@ type (
 	Manifest struct {
 		Name       string                     `yaml:"-"`
 		APIVersion string                     `yaml:"apiVersion,omitempty"`
+		Strategy   string                     `yaml:"strategy,omitempty"`
+		Backend    *config.Backend            `yaml:"backend,omitempty"`
+		Providers  catalog.Providers          `yaml:"providers,omitempty"`
 		Metadata   types.Metadata             `yaml:"metadata,omitempty"`
 		Services   map[string]catalog.Service `yaml:"services,omitempty"`
 		filepath   string                     `yaml:"-"`
+		raw        []byte                     `yaml:"-"`
+	}
+
+	// EditOptions holds the non-interactive edits applied by `skiff edit`.
+	// Paths are dotted and relative to the service, or to the metadata when
+	// editing a manifest.
+	EditOptions struct {
+		Set      []string
+		SetJSON  []string
+		Unset    []string
+		FromFile string
+		Yes      bool
+	}
+
+	// MoveOptions controls how `skiff service mv` relocates a service.
+	MoveOptions struct {
+		ToManifest    string
+		MoveGenerated bool
+		Force         bool
 	}
 )
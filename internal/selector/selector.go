@@ -0,0 +1,303 @@
+package selector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Parse parses a label selector. The syntax follows Kubernetes label
+// selectors, with `||` separating alternative groups:
+//
+//   - env=prod, env==prod     the label equals the value
+//   - team!=data              the label is missing or differs from the value
+//   - env in (prod,staging)   the label equals one of the values
+//   - env notin (dev,test)    the label is missing or equals none of the values
+//   - tier                    the label is set
+//   - !deprecated             the label is not set
+//
+// Requirements separated by commas must all match. Groups separated by `||`
+// are alternatives, so `env=prod,tier=web || env=staging` selects production
+// web services and everything in staging. An empty selector matches all labels.
+func Parse(input string) (*Selector, error) {
+	p := &parser{input: input}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	return p.parseSelector()
+}
+
+// Matches reports whether labels satisfy the selector. Label values of any
+// type are compared through their string form, so `account_id=123456789012`
+// matches both the number and the quoted string.
+func (s *Selector) Matches(labels map[string]any) bool {
+	if s == nil || len(s.Groups) == 0 {
+		return true
+	}
+
+	for _, group := range s.Groups {
+		if group.Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether every requirement of the group is satisfied.
+func (g Group) Matches(labels map[string]any) bool {
+	for _, requirement := range g {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether labels satisfy the requirement.
+func (r Requirement) Matches(labels map[string]any) bool {
+	raw, exists := labels[r.Key]
+	value := ""
+	if exists && raw != nil {
+		value = fmt.Sprint(raw)
+	}
+
+	switch r.Operator {
+	case Exists:
+		return exists
+	case DoesNotExist:
+		return !exists
+	case Equals:
+		return exists && value == r.Values[0]
+	case NotEquals:
+		return !exists || value != r.Values[0]
+	case In:
+		return exists && slices.Contains(r.Values, value)
+	case NotIn:
+		return !exists || !slices.Contains(r.Values, value)
+	}
+	return false
+}
+
+func (s *Selector) String() string {
+	groups := make([]string, 0, len(s.Groups))
+	for _, group := range s.Groups {
+		groups = append(groups, group.String())
+	}
+	return strings.Join(groups, " || ")
+}
+
+func (g Group) String() string {
+	requirements := make([]string, 0, len(g))
+	for _, requirement := range g {
+		requirements = append(requirements, requirement.String())
+	}
+	return strings.Join(requirements, ",")
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	default:
+		return r.Key + string(r.Operator) + r.Values[0]
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid label selector %q: %s at position %d", e.Input, e.Message, e.Position+1)
+}
+
+type parser struct {
+	input  string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return &SyntaxError{Input: p.input, Position: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) tokenize() error {
+	runes := []rune(p.input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == ',':
+			p.tokens = append(p.tokens, token{kind: tokenComma, value: ",", pos: i})
+			i++
+		case r == '(':
+			p.tokens = append(p.tokens, token{kind: tokenOpenParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			p.tokens = append(p.tokens, token{kind: tokenCloseParen, value: ")", pos: i})
+			i++
+		case r == '|':
+			if i+1 >= len(runes) || runes[i+1] != '|' {
+				return p.errorf(i, `expected "||"`)
+			}
+			p.tokens = append(p.tokens, token{kind: tokenOr, value: "||", pos: i})
+			i += 2
+		case r == '!':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				p.tokens = append(p.tokens, token{kind: tokenNotEquals, value: "!=", pos: i})
+				i += 2
+				continue
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNot, value: "!", pos: i})
+			i++
+		case r == '=':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				p.tokens = append(p.tokens, token{kind: tokenEquals, value: "==", pos: i})
+				i += 2
+				continue
+			}
+			p.tokens = append(p.tokens, token{kind: tokenEquals, value: "=", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !isSeparator(runes[i]) {
+				i++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenIdentifier, value: string(runes[start:i]), pos: start})
+		}
+	}
+
+	p.tokens = append(p.tokens, token{kind: tokenEOF, pos: len(runes)})
+	return nil
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(",()|!=", r)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseSelector() (*Selector, error) {
+	selector := &Selector{}
+	if p.peek().kind == tokenEOF {
+		return selector, nil
+	}
+
+	for {
+		group, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		selector.Groups = append(selector.Groups, group)
+
+		switch t := p.next(); t.kind {
+		case tokenEOF:
+			return selector, nil
+		case tokenOr:
+			continue
+		default:
+			return nil, p.errorf(t.pos, `unexpected %q, expected "," or "||"`, t.value)
+		}
+	}
+}
+
+func (p *parser) parseGroup() (Group, error) {
+	var group Group
+	for {
+		requirement, err := p.parseRequirement()
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, requirement)
+
+		if p.peek().kind != tokenComma {
+			return group, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseRequirement() (Requirement, error) {
+	t := p.next()
+
+	if t.kind == tokenNot {
+		key := p.next()
+		if key.kind != tokenIdentifier {
+			return Requirement{}, p.errorf(key.pos, `expected label key after "!"`)
+		}
+		return Requirement{Key: key.value, Operator: DoesNotExist}, nil
+	}
+
+	if t.kind != tokenIdentifier {
+		if t.kind == tokenEOF {
+			return Requirement{}, p.errorf(t.pos, "expected label key")
+		}
+		return Requirement{}, p.errorf(t.pos, "expected label key, found %q", t.value)
+	}
+
+	key := t.value
+	switch op := p.peek(); {
+	case op.kind == tokenEquals || op.kind == tokenNotEquals:
+		p.next()
+		value := p.next()
+		if value.kind != tokenIdentifier {
+			return Requirement{}, p.errorf(value.pos, "expected value after %q", key+op.value)
+		}
+		operator := Equals
+		if op.kind == tokenNotEquals {
+			operator = NotEquals
+		}
+		return Requirement{Key: key, Operator: operator, Values: []string{value.value}}, nil
+
+	case op.kind == tokenIdentifier && (op.value == string(In) || op.value == string(NotIn)):
+		p.next()
+		values, err := p.parseValues(key, op)
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: Operator(op.value), Values: values}, nil
+
+	case op.kind == tokenIdentifier:
+		return Requirement{}, p.errorf(op.pos, `unexpected %q after key %q, expected "=", "!=", "in" or "notin"`, op.value, key)
+
+	default:
+		return Requirement{Key: key, Operator: Exists}, nil
+	}
+}
+
+func (p *parser) parseValues(key string, op token) ([]string, error) {
+	if t := p.next(); t.kind != tokenOpenParen {
+		return nil, p.errorf(t.pos, `expected "(" after "%s %s"`, key, op.value)
+	}
+
+	var values []string
+	for {
+		value := p.next()
+		if value.kind != tokenIdentifier {
+			return nil, p.errorf(value.pos, "expected value in %q list", key+" "+op.value)
+		}
+		values = append(values, value.value)
+
+		switch t := p.next(); t.kind {
+		case tokenComma:
+			continue
+		case tokenCloseParen:
+			return values, nil
+		default:
+			return nil, p.errorf(t.pos, `expected "," or ")" in %q list`, key+" "+op.value)
+		}
+	}
+}
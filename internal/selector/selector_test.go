@@ -0,0 +1,106 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Empty selector", input: "", expected: ""},
+		{name: "Equality", input: "env=prod", expected: "env=prod"},
+		{name: "Double equals", input: "env==prod", expected: "env=prod"},
+		{name: "Inequality", input: "team != data", expected: "team!=data"},
+		{name: "Set membership", input: "env in (prod, staging)", expected: "env in (prod,staging)"},
+		{name: "Set exclusion", input: "env notin (dev)", expected: "env notin (dev)"},
+		{name: "Existence", input: "tier,!deprecated", expected: "tier,!deprecated"},
+		{name: "Or groups", input: "env=prod,tier=web || env=staging", expected: "env=prod,tier=web || env=staging"},
+		{name: "Values with dots and slashes", input: "region=us-east-1,source=github.com/org/repo", expected: "region=us-east-1,source=github.com/org/repo"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selector, err := Parse(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, selector.String())
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Missing value", input: "env=", expected: `invalid label selector "env=": expected value after "env=" at position 5`},
+		{name: "Missing parenthesis", input: "env in prod", expected: `invalid label selector "env in prod": expected "(" after "env in" at position 8`},
+		{name: "Unclosed list", input: "env in (prod", expected: `invalid label selector "env in (prod": expected "," or ")" in "env in" list at position 13`},
+		{name: "Single pipe", input: "env=prod | env=dev", expected: `invalid label selector "env=prod | env=dev": expected "||" at position 10`},
+		{name: "Dangling comma", input: "env=prod,", expected: `invalid label selector "env=prod,": expected label key at position 10`},
+		{name: "Unknown operator", input: "env is prod", expected: `invalid label selector "env is prod": unexpected "is" after key "env", expected "=", "!=", "in" or "notin" at position 5`},
+		{name: "Missing key after not", input: "!=prod", expected: `invalid label selector "!=prod": expected label key, found "!=" at position 1`},
+		{name: "Empty group", input: "env=prod ||", expected: `invalid label selector "env=prod ||": expected label key at position 12`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.input)
+			require.Error(t, err)
+			assert.IsType(t, &SyntaxError{}, err)
+			assert.Equal(t, tc.expected, err.Error())
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	labels := map[string]any{
+		"env":        "prod",
+		"team":       "platform",
+		"tier":       "web",
+		"account_id": 123456789012,
+		"replicas":   3,
+		"public":     true,
+		"legacy_id":  "012345678901",
+	}
+
+	testCases := []struct {
+		name     string
+		selector string
+		expected bool
+	}{
+		{name: "Empty selector", selector: "", expected: true},
+		{name: "Equality", selector: "env=prod", expected: true},
+		{name: "Equality mismatch", selector: "env=dev", expected: false},
+		{name: "Inequality", selector: "team!=data", expected: true},
+		{name: "Inequality on missing key", selector: "owner!=data", expected: true},
+		{name: "In", selector: "env in (staging,prod)", expected: true},
+		{name: "In on missing key", selector: "owner in (data)", expected: false},
+		{name: "Not in", selector: "env notin (staging,prod)", expected: false},
+		{name: "Not in on missing key", selector: "owner notin (data)", expected: true},
+		{name: "Exists", selector: "tier", expected: true},
+		{name: "Does not exist", selector: "!deprecated", expected: true},
+		{name: "Does not exist mismatch", selector: "!tier", expected: false},
+		{name: "Numeric account id", selector: "account_id=123456789012", expected: true},
+		{name: "Numeric account id in set", selector: "account_id in (111111111111,123456789012)", expected: true},
+		{name: "Leading zero string", selector: "legacy_id=012345678901", expected: true},
+		{name: "Integer", selector: "replicas=3", expected: true},
+		{name: "Boolean", selector: "public=true", expected: true},
+		{name: "All requirements of a group", selector: "env=prod,tier=api", expected: false},
+		{name: "Any group", selector: "env=prod,tier=api || team=platform", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selector, err := Parse(tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, selector.Matches(labels))
+		})
+	}
+}
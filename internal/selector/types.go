@@ -0,0 +1,58 @@
+package selector
+
+type (
+	// Operator is the comparison a requirement applies to a label.
+	Operator string
+
+	// Requirement is a single condition on one label key.
+	Requirement struct {
+		Key      string
+		Operator Operator
+		Values   []string
+	}
+
+	// Group is a set of requirements that must all match.
+	Group []Requirement
+
+	// Selector matches labels when any of its groups matches. The zero value
+	// matches everything.
+	Selector struct {
+		Groups []Group
+	}
+
+	// SyntaxError describes where and why a selector failed to parse.
+	SyntaxError struct {
+		Input    string
+		Position int
+		Message  string
+	}
+
+	tokenKind int
+
+	token struct {
+		kind  tokenKind
+		value string
+		pos   int
+	}
+)
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenComma
+	tokenOr
+	tokenNot
+	tokenEquals
+	tokenNotEquals
+	tokenOpenParen
+	tokenCloseParen
+)
@@ -0,0 +1,341 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/manifest"
+	"github.com/nyambati/skiff/internal/selector"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// stateFile is the object name terragrunt stores below the key derived from
+// path_relative_to_include() when skiff configures no backend.
+const stateFile = "terraform.tfstate"
+
+// PlanMigration compares, for every selected service, the target path of the
+// previous strategy from with the one of the strategy configured now. From is
+// a strategy file, a strategy name or a path template. Nothing is moved, see
+// ApplyMigration.
+func PlanMigration(ctx context.Context, manifestID, labels, from string) (*Migration, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := selector.Parse(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := loadCandidate(cfg, from)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := manifest.ReadAll(ctx, manifestID)
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &Migration{Root: cfg.Terragrunt}
+	sources := map[string]string{}
+	targets := map[string]string{}
+
+	for _, m := range manifests {
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			if !labelSelector.Matches(serviceLabels(m, name)) {
+				continue
+			}
+
+			move := Move{Manifest: m.Name, Service: name}
+
+			if move.From, err = m.TargetPathFor(ctx, name, previous); err != nil {
+				return nil, fmt.Errorf("failed to resolve the previous path of %s: %w", move.ID(), err)
+			}
+
+			if move.To, err = m.TargetPath(ctx, name); err != nil {
+				return nil, fmt.Errorf("failed to resolve the new path of %s: %w", move.ID(), err)
+			}
+
+			if move.FromKey, move.ToKey, err = stateKeys(ctx, m, name, previous, move); err != nil {
+				return nil, fmt.Errorf("failed to resolve the state keys of %s: %w", move.ID(), err)
+			}
+
+			if owner, exists := sources[move.From]; exists {
+				return nil, skiff.NewTargetPathCollisionError(move.From, owner, move.ID())
+			}
+			if owner, exists := targets[move.To]; exists {
+				return nil, skiff.NewTargetPathCollisionError(move.To, owner, move.ID())
+			}
+			sources[move.From] = move.ID()
+			targets[move.To] = move.ID()
+
+			move.Status, move.Reason = migrationStatus(cfg.Terragrunt, move.From, move.To)
+			migration.Moves = append(migration.Moves, move)
+		}
+	}
+
+	return migration, nil
+}
+
+// stateKeys resolves the state key of the named service with the previous
+// strategy and the current one from the key template of the backend. Without
+// a backend the keys are assumed to follow path_relative_to_include().
+func stateKeys(ctx context.Context, m *manifest.Manifest, name string, previous *config.Strategy, move Move) (string, string, error) {
+	from, err := m.StateKeyFor(ctx, name, previous)
+	if err != nil {
+		return "", "", err
+	}
+	if from == "" {
+		return path.Join(move.From, stateFile), path.Join(move.To, stateFile), nil
+	}
+
+	to, err := m.StateKeyFor(ctx, name, nil)
+	if err != nil {
+		return "", "", err
+	}
+	return from, to, nil
+}
+
+// migrationStatus decides what happens to the folder of a service moving from
+// one path to another, both relative to root.
+func migrationStatus(root, from, to string) (MoveStatus, string) {
+	switch {
+	case from == to:
+		return MoveUnchanged, ""
+	case !utils.FileExists(filepath.Join(root, from)):
+		return MoveNotGenerated, ""
+	case utils.FileExists(filepath.Join(root, to)):
+		return MoveConflict, fmt.Sprintf("%s already exists", filepath.Join(root, to))
+	case isWithin(to, from) || isWithin(from, to):
+		return MoveConflict, "the old and new folders are nested in each other"
+	}
+	return MovePending, ""
+}
+
+// isWithin reports whether the slash-delimited path is below parent.
+func isWithin(p, parent string) bool {
+	return strings.HasPrefix(p, parent+"/")
+}
+
+// ID returns the manifest/service identifier of the move.
+func (m Move) ID() string {
+	return m.Manifest + "/" + m.Service
+}
+
+// Changed reports whether a generated folder, and its state, moves to
+// another folder. Services never generated have nothing to move.
+func (m Move) Changed() bool {
+	switch m.Status {
+	case MovePending, MoveDone, MoveConflict:
+		return true
+	}
+	return false
+}
+
+// StateMoves reports whether the state key of the service changes with its
+// folder.
+func (m Move) StateMoves() bool {
+	return m.Changed() && m.FromKey != m.ToKey
+}
+
+// Conflicts returns the moves that cannot be applied.
+func (m *Migration) Conflicts() []Move {
+	var conflicts []Move
+	for _, move := range m.Moves {
+		if move.Status == MoveConflict {
+			conflicts = append(conflicts, move)
+		}
+	}
+	return conflicts
+}
+
+// ApplyMigration moves the generated folder of every pending move, including
+// its .terragrunt-cache, and removes the old parent folders left empty. A
+// migration with conflicts is refused as a whole so the layout is never left
+// half migrated.
+func ApplyMigration(migration *Migration) error {
+	if conflicts := migration.Conflicts(); len(conflicts) > 0 {
+		ids := make([]string, 0, len(conflicts))
+		for _, move := range conflicts {
+			ids = append(ids, move.ID())
+		}
+		return fmt.Errorf("cannot migrate, resolve the conflicts of %s first", strings.Join(ids, ", "))
+	}
+
+	for i, move := range migration.Moves {
+		if move.Status != MovePending {
+			continue
+		}
+
+		oldFolder := filepath.Join(migration.Root, move.From)
+		newFolder := filepath.Join(migration.Root, move.To)
+
+		if err := utils.CreateDirectory(filepath.Dir(newFolder)); err != nil {
+			return err
+		}
+
+		if err := os.Rename(oldFolder, newFolder); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", oldFolder, newFolder, err)
+		}
+
+		pruneEmptyParents(migration.Root, filepath.Dir(oldFolder))
+		migration.Moves[i].Status = MoveDone
+		logrus.Infof("✅ moved %s to %s\n", oldFolder, newFolder)
+	}
+	return nil
+}
+
+// pruneEmptyParents removes dir and its parents up to, but excluding, root as
+// long as they are empty.
+func pruneEmptyParents(root, dir string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// WriteMigration writes the migration plan followed by the remote state keys
+// that change with it.
+func WriteMigration(w io.Writer, migration *Migration, format string) error {
+	return utils.WriteOutput(w, format, migration, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MANIFEST\tSERVICE\tSTATUS\tFROM\tTO")
+		for _, move := range migration.Moves {
+			status := string(move.Status)
+			if move.Reason != "" {
+				status += " (" + move.Reason + ")"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", move.Manifest, move.Service, status, move.From, move.To)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		var changed []Move
+		for _, move := range migration.Moves {
+			if move.Changed() {
+				changed = append(changed, move)
+			}
+		}
+
+		if len(changed) == 0 {
+			fmt.Fprintln(w, "\nℹ️  no generated folder changes, nothing to migrate")
+			return nil
+		}
+
+		fmt.Fprintln(w, "\n⚠️  moved folders keep the relative config_path and include paths rendered for")
+		fmt.Fprintln(w, "   their old depth, run skiff generate once they are moved.")
+
+		var stateMoves []Move
+		for _, move := range changed {
+			if move.StateMoves() {
+				stateMoves = append(stateMoves, move)
+			}
+		}
+
+		if len(stateMoves) == 0 {
+			fmt.Fprintln(w, "\nℹ️  the backend key template does not use var.path, the state keys do not change")
+			return nil
+		}
+
+		fmt.Fprintln(w, "\nℹ️  the state keys change as well, copy each state object in the backend, or use")
+		fmt.Fprintln(w, "   --script, before the next plan:")
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, move := range stateMoves {
+			fmt.Fprintf(tw, "   %s\t%s\t->\t%s\n", move.ID(), move.FromKey, move.ToKey)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		if len(stateMoves) < len(changed) {
+			fmt.Fprintln(w, "\n   the state keys of the other moved services do not change.")
+		}
+		return nil
+	})
+}
+
+// WriteMigrationScript writes a shell script that migrates every pending move
+// with its state: the state is pulled from the old folder and the folder is
+// moved, the moved services are generated again so their relative paths match
+// the new depth, and the state is pushed from the new folder. States whose key
+// does not change stay where they are. It replaces ApplyMigration.
+func WriteMigrationScript(w io.Writer, migration *Migration) error {
+	var b strings.Builder
+
+	fmt.Fprintln(&b, "#!/usr/bin/env bash")
+	fmt.Fprintln(&b, "# generated by skiff strategy migrate, review before running")
+	fmt.Fprintln(&b, "set -euo pipefail")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, `STATE_DIR="$(mktemp -d)"`)
+
+	var pending []Move
+	for _, move := range migration.Moves {
+		switch move.Status {
+		case MovePending:
+		case MoveConflict:
+			fmt.Fprintf(&b, "\n# %s: skipped, %s\n", move.ID(), move.Reason)
+			continue
+		default:
+			continue
+		}
+		pending = append(pending, move)
+
+		oldFolder := filepath.Join(migration.Root, move.From)
+		newFolder := filepath.Join(migration.Root, move.To)
+
+		fmt.Fprintf(&b, "\n# %s\n", move.ID())
+		if move.StateMoves() {
+			fmt.Fprintf(&b, "terragrunt state pull --terragrunt-working-dir %s > %s\n", shellQuote(oldFolder), scriptState(move))
+		}
+		fmt.Fprintf(&b, "mkdir -p %s\n", shellQuote(filepath.Dir(newFolder)))
+		fmt.Fprintf(&b, "mv %s %s\n", shellQuote(oldFolder), shellQuote(newFolder))
+	}
+
+	if len(pending) > 0 {
+		fmt.Fprintln(&b, "\n# relative config_path and include paths were rendered for the old folders")
+		var manifests []string
+		for _, move := range pending {
+			if !slices.Contains(manifests, move.Manifest) {
+				manifests = append(manifests, move.Manifest)
+				fmt.Fprintf(&b, "skiff generate --manifest %s\n", shellQuote(move.Manifest))
+			}
+		}
+	}
+
+	for _, move := range pending {
+		if !move.StateMoves() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n# %s\n", move.ID())
+		fmt.Fprintf(&b, "terragrunt state push --terragrunt-working-dir %s %s\n", shellQuote(filepath.Join(migration.Root, move.To)), scriptState(move))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// scriptState is the file the migration script keeps the state of move in.
+func scriptState(move Move) string {
+	return fmt.Sprintf(`"$STATE_DIR"/%s`, shellQuote(move.Manifest+"-"+move.Service+".tfstate"))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
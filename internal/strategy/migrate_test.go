@@ -0,0 +1,207 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMigration uses the preview fixtures with a real output folder in which
+// the given folders have been generated.
+func setupMigration(t *testing.T, template string, generated ...string) (context.Context, string) {
+	ctx := setupPreview(t, template)
+	cfg, err := config.FromContext(ctx)
+	require.NoError(t, err)
+
+	cfg.Terragrunt = filepath.Join(t.TempDir(), "terragrunt")
+	for _, folder := range generated {
+		path := filepath.Join(cfg.Terragrunt, folder)
+		require.NoError(t, os.MkdirAll(filepath.Join(path, ".terragrunt-cache"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(path, "terragrunt.hcl"), []byte(folder), 0644))
+	}
+	return ctx, cfg.Terragrunt
+}
+
+func TestPlanMigration(t *testing.T) {
+	t.Run("Pending and not generated folders", func(t *testing.T) {
+		ctx, root := setupMigration(t, "{{ var.group }}/{{ var.service }}", "111111111111/us-east-1/vpc")
+
+		migration, err := PlanMigration(ctx, "", "", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+		require.NoError(t, err)
+		assert.Equal(t, &Migration{
+			Root: root,
+			Moves: []Move{
+				{Manifest: "workload", Service: "eks", From: "111111111111/us-east-1/eks", To: "compute/eks", FromKey: "111111111111/us-east-1/eks/terraform.tfstate", ToKey: "compute/eks/terraform.tfstate", Status: MoveNotGenerated},
+				{Manifest: "workload", Service: "vpc", From: "111111111111/us-east-1/vpc", To: "network/vpc", FromKey: "111111111111/us-east-1/vpc/terraform.tfstate", ToKey: "network/vpc/terraform.tfstate", Status: MovePending},
+			},
+		}, migration)
+	})
+
+	t.Run("Folders never generated move no state", func(t *testing.T) {
+		ctx, _ := setupMigration(t, "{{ var.group }}/{{ var.service }}")
+
+		migration, err := PlanMigration(ctx, "", "", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+		require.NoError(t, err)
+		for _, move := range migration.Moves {
+			assert.Equal(t, MoveNotGenerated, move.Status)
+			assert.False(t, move.Changed())
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteMigration(&buf, migration, utils.OutputText))
+		assert.Contains(t, buf.String(), "nothing to migrate")
+		assert.NotContains(t, buf.String(), "state keys change")
+		assert.NotContains(t, buf.String(), "->")
+		assert.NotContains(t, buf.String(), "run skiff generate")
+	})
+
+	t.Run("Only generated folders move state", func(t *testing.T) {
+		ctx, _ := setupMigration(t, "{{ var.group }}/{{ var.service }}", "111111111111/us-east-1/vpc")
+
+		migration, err := PlanMigration(ctx, "", "", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteMigration(&buf, migration, utils.OutputText))
+		assert.Contains(t, buf.String(), "workload/vpc  111111111111/us-east-1/vpc/terraform.tfstate  ->  network/vpc/terraform.tfstate")
+		assert.NotContains(t, buf.String(), "workload/eks  ")
+	})
+
+	t.Run("State keys of the backend", func(t *testing.T) {
+		ctx, _ := setupMigration(t, "{{ var.group }}/{{ var.service }}", "111111111111/us-east-1/vpc")
+		cfg, err := config.FromContext(ctx)
+		require.NoError(t, err)
+		cfg.Backend = &config.Backend{Type: config.BackendGCS, Config: map[string]any{"bucket": "state"}}
+
+		migration, err := PlanMigration(ctx, "", "tier=network", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+		require.NoError(t, err)
+		require.Len(t, migration.Moves, 1)
+		assert.Equal(t, "111111111111/us-east-1/vpc", migration.Moves[0].FromKey)
+		assert.Equal(t, "network/vpc", migration.Moves[0].ToKey)
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteMigration(&buf, migration, utils.OutputText))
+		assert.Contains(t, buf.String(), "workload/vpc  111111111111/us-east-1/vpc  ->  network/vpc")
+	})
+
+	t.Run("State keys without var.path", func(t *testing.T) {
+		ctx, _ := setupMigration(t, "{{ var.group }}/{{ var.service }}", "111111111111/us-east-1/vpc")
+		cfg, err := config.FromContext(ctx)
+		require.NoError(t, err)
+		cfg.Backend = &config.Backend{Type: config.BackendS3, Key: "{{ var.account_id }}/{{ var.service }}.tfstate", Config: map[string]any{"bucket": "state"}}
+
+		migration, err := PlanMigration(ctx, "", "tier=network", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+		require.NoError(t, err)
+		require.Len(t, migration.Moves, 1)
+		assert.Equal(t, "111111111111/vpc.tfstate", migration.Moves[0].FromKey)
+		assert.Equal(t, "111111111111/vpc.tfstate", migration.Moves[0].ToKey)
+		assert.False(t, migration.Moves[0].StateMoves())
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteMigration(&buf, migration, utils.OutputText))
+		assert.Contains(t, buf.String(), "the backend key template does not use var.path, the state keys do not change")
+	})
+
+	t.Run("Unchanged folders", func(t *testing.T) {
+		ctx, _ := setupMigration(t, "{{ var.group }}/{{ var.service }}")
+
+		migration, err := PlanMigration(ctx, "", "tier=network", "by-group")
+		require.NoError(t, err)
+		require.Len(t, migration.Moves, 1)
+		assert.Equal(t, MoveUnchanged, migration.Moves[0].Status)
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteMigration(&buf, migration, utils.OutputTable))
+		assert.Contains(t, buf.String(), "nothing to migrate")
+	})
+
+	t.Run("Existing destination conflicts", func(t *testing.T) {
+		ctx, root := setupMigration(t, "{{ var.group }}/{{ var.service }}", "111111111111/us-east-1/vpc", "network/vpc")
+
+		migration, err := PlanMigration(ctx, "", "tier=network", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+		require.NoError(t, err)
+		assert.Equal(t, MoveConflict, migration.Moves[0].Status)
+		assert.Equal(t, filepath.Join(root, "network/vpc")+" already exists", migration.Moves[0].Reason)
+
+		err = ApplyMigration(migration)
+		assert.EqualError(t, err, "cannot migrate, resolve the conflicts of workload/vpc first")
+		assert.DirExists(t, filepath.Join(root, "111111111111/us-east-1/vpc"))
+	})
+
+	t.Run("Colliding previous layout", func(t *testing.T) {
+		ctx, _ := setupMigration(t, "{{ var.group }}/{{ var.service }}")
+
+		_, err := PlanMigration(ctx, "", "", "flat")
+		assert.EqualError(t, err, "services workload/eks and workload/vpc resolve to the same target path 111111111111, adjust the strategy or the service labels")
+	})
+}
+
+func TestApplyMigration(t *testing.T) {
+	ctx, root := setupMigration(t, "{{ var.group }}/{{ var.service }}", "111111111111/us-east-1/vpc", "111111111111/us-east-1/eks")
+
+	migration, err := PlanMigration(ctx, "", "", "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+	require.NoError(t, err)
+	require.NoError(t, ApplyMigration(migration))
+
+	for _, move := range migration.Moves {
+		assert.Equal(t, MoveDone, move.Status)
+		assert.DirExists(t, filepath.Join(root, move.To, ".terragrunt-cache"))
+
+		content, err := os.ReadFile(filepath.Join(root, move.To, "terragrunt.hcl"))
+		require.NoError(t, err)
+		assert.Equal(t, move.From, string(content))
+	}
+
+	// the emptied account folder is pruned, the output root is kept
+	assert.NoDirExists(t, filepath.Join(root, "111111111111"))
+	assert.DirExists(t, root)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMigration(&buf, migration, utils.OutputText))
+	assert.Contains(t, buf.String(), "run skiff generate once they are moved")
+}
+
+func TestWriteMigrationScript(t *testing.T) {
+	migration := &Migration{
+		Root: "terragrunt",
+		Moves: []Move{
+			{Manifest: "workload", Service: "vpc", From: "old/vpc", To: "new/vpc", FromKey: "old/vpc/terraform.tfstate", ToKey: "new/vpc/terraform.tfstate", Status: MovePending},
+			{Manifest: "workload", Service: "sqs", From: "old/sqs", To: "new/sqs", FromKey: "sqs.tfstate", ToKey: "sqs.tfstate", Status: MovePending},
+			{Manifest: "workload", Service: "eks", From: "old/eks", To: "new/eks", Status: MoveConflict, Reason: "terragrunt/new/eks already exists"},
+			{Manifest: "workload", Service: "rds", From: "rds", To: "rds", Status: MoveUnchanged},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMigrationScript(&buf, migration))
+	assert.Equal(t, `#!/usr/bin/env bash
+# generated by skiff strategy migrate, review before running
+set -euo pipefail
+
+STATE_DIR="$(mktemp -d)"
+
+# workload/vpc
+terragrunt state pull --terragrunt-working-dir 'terragrunt/old/vpc' > "$STATE_DIR"/'workload-vpc.tfstate'
+mkdir -p 'terragrunt/new'
+mv 'terragrunt/old/vpc' 'terragrunt/new/vpc'
+
+# workload/sqs
+mkdir -p 'terragrunt/new'
+mv 'terragrunt/old/sqs' 'terragrunt/new/sqs'
+
+# workload/eks: skipped, terragrunt/new/eks already exists
+
+# relative config_path and include paths were rendered for the old folders
+skiff generate --manifest 'workload'
+
+# workload/vpc
+terragrunt state push --terragrunt-working-dir 'terragrunt/new/vpc' "$STATE_DIR"/'workload-vpc.tfstate'
+`, buf.String())
+}
@@ -0,0 +1,245 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/manifest"
+	"github.com/nyambati/skiff/internal/selector"
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+// BuildPreview resolves the target path of every selected service without
+// generating anything. When candidate is set, services are resolved with that
+// strategy and compared with the paths of the current layout, otherwise the
+// configured strategies are previewed as is. Candidate is either a strategy
+// file or the name of a strategy in the strategies folder.
+func BuildPreview(ctx context.Context, manifestID, labels, candidate string) (*Preview, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := selector.Parse(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var override *config.Strategy
+	if candidate != "" {
+		if override, err = loadCandidate(cfg, candidate); err != nil {
+			return nil, err
+		}
+	}
+
+	manifests, err := manifest.ReadAll(ctx, manifestID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{Root: cfg.Terragrunt}
+
+	for _, m := range manifests {
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			entry := PreviewEntry{Manifest: m.Name, Service: name}
+
+			if !labelSelector.Matches(serviceLabels(m, name)) {
+				continue
+			}
+
+			current, err := m.TargetPath(ctx, name)
+			if err != nil {
+				entry.Error = err.Error()
+				preview.Entries = append(preview.Entries, entry)
+				continue
+			}
+			entry.Path = current
+
+			if override != nil {
+				entry.CurrentPath = current
+				if entry.Path, err = m.TargetPathFor(ctx, name, override); err != nil {
+					entry.Path = ""
+					entry.Error = err.Error()
+				}
+				entry.Moved = entry.Error == "" && entry.Path != entry.CurrentPath
+			}
+
+			preview.Entries = append(preview.Entries, entry)
+		}
+	}
+
+	preview.flag()
+	return preview, nil
+}
+
+// serviceLabels returns the labels the service would be selected by, the
+// manifest metadata overlaid with the service labels.
+func serviceLabels(m *manifest.Manifest, name string) map[string]any {
+	labels := map[string]any{}
+	maps.Copy(labels, m.Metadata)
+	maps.Copy(labels, m.Services[name].Labels)
+	return labels
+}
+
+// loadCandidate loads a strategy given as a file, a path template or the name
+// of a strategy in the strategies folder.
+func loadCandidate(cfg *config.Config, candidate string) (*config.Strategy, error) {
+	if strings.Contains(candidate, "{{") {
+		return &config.Strategy{Template: candidate}, nil
+	}
+	if utils.FileExists(candidate) {
+		return config.ReadStrategyFile(candidate)
+	}
+	return cfg.LoadStrategy(candidate)
+}
+
+// flag marks the services that resolve to the same folder.
+func (p *Preview) flag() {
+	byPath := map[string][]int{}
+
+	for i := range p.Entries {
+		entry := &p.Entries[i]
+		if entry.Error != "" {
+			continue
+		}
+
+		byPath[entry.Path] = append(byPath[entry.Path], i)
+	}
+
+	for _, indexes := range byPath {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, i := range indexes {
+			for _, j := range indexes {
+				if i != j {
+					p.Entries[i].Collisions = append(p.Entries[i].Collisions, p.Entries[j].ID())
+				}
+			}
+		}
+	}
+}
+
+// ID returns the manifest/service identifier of the entry.
+func (e PreviewEntry) ID() string {
+	return e.Manifest + "/" + e.Service
+}
+
+// HasIssues reports whether any service failed to resolve, including paths
+// that leave the output root, or collides with another service.
+func (p *Preview) HasIssues() bool {
+	for _, entry := range p.Entries {
+		if entry.Error != "" || len(entry.Collisions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WritePreview writes the preview to w. The table format renders the
+// resolved paths as a directory tree followed by the flagged services.
+func WritePreview(w io.Writer, preview *Preview, format string) error {
+	return utils.WriteOutput(w, format, preview, func(w io.Writer) error {
+		root := newTreeNode()
+		var failed []PreviewEntry
+		var collisions, moved int
+
+		for _, entry := range preview.Entries {
+			if entry.Error != "" {
+				failed = append(failed, entry)
+				continue
+			}
+
+			if len(entry.Collisions) > 0 {
+				collisions++
+			}
+			if entry.Moved {
+				moved++
+			}
+			root.add(strings.Split(filepath.ToSlash(entry.Path), "/"), entry)
+		}
+
+		fmt.Fprintln(w, preview.Root)
+		root.write(w, "")
+
+		if len(failed) > 0 {
+			fmt.Fprintln(w, "\n❌ services that failed to resolve:")
+			for _, entry := range failed {
+				fmt.Fprintf(w, "  %s: %s\n", entry.ID(), entry.Error)
+			}
+		}
+
+		fmt.Fprintf(
+			w, "\n%d services, %d colliding, %d moving, %d failed\n",
+			len(preview.Entries), collisions, moved, len(failed),
+		)
+		return nil
+	})
+}
+
+type treeNode struct {
+	children map[string]*treeNode
+	entries  []PreviewEntry
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{children: map[string]*treeNode{}}
+}
+
+func (n *treeNode) add(segments []string, entry PreviewEntry) {
+	if len(segments) == 0 {
+		n.entries = append(n.entries, entry)
+		return
+	}
+
+	child, ok := n.children[segments[0]]
+	if !ok {
+		child = newTreeNode()
+		n.children[segments[0]] = child
+	}
+	child.add(segments[1:], entry)
+}
+
+func (n *treeNode) write(w io.Writer, prefix string) {
+	names := slices.Sorted(maps.Keys(n.children))
+	for i, name := range names {
+		child := n.children[name]
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		fmt.Fprintf(w, "%s%s%s%s\n", prefix, branch, name, child.annotation())
+		child.write(w, prefix+indent)
+	}
+}
+
+// annotation describes the services rendered into the folder, if any.
+func (n *treeNode) annotation() string {
+	if len(n.entries) == 0 {
+		return ""
+	}
+
+	var ids, notes []string
+	for _, entry := range n.entries {
+		ids = append(ids, entry.ID())
+		if entry.Moved {
+			notes = append(notes, fmt.Sprintf("moved from %s", entry.CurrentPath))
+		}
+	}
+
+	annotation := "  ← " + strings.Join(ids, ", ")
+	if len(n.entries) > 1 {
+		notes = append(notes, "⚠️  collision")
+	}
+	if len(notes) > 0 {
+		annotation += " [" + strings.Join(notes, "; ") + "]"
+	}
+	return annotation
+}
@@ -0,0 +1,123 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPreview(t *testing.T, template string) context.Context {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Path: config.Path{
+			Manifests:  filepath.Join(tempDir, "manifests"),
+			Terragrunt: "terragrunt",
+		},
+		Strategy: config.Strategy{Template: template},
+	}
+
+	files := map[string]string{
+		"manifests/catalog.yaml": "types:\n  vpc:\n    group: network\n  eks:\n    group: compute\n",
+		"manifests/workload.yaml": `metadata:
+  account_id: "111111111111"
+services:
+  vpc:
+    type: vpc
+    region: us-east-1
+    labels:
+      tier: network
+  eks:
+    type: eks
+    region: us-east-1
+`,
+		"strategies/by-group.yaml": "template: \"{{ var.group }}/{{ var.service }}\"\n",
+		"strategies/flat.yaml":     "template: \"{{ var.account_id }}\"\n",
+		"strategies/escape.yaml":   "template: \"../{{ var.service }}\"\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return context.WithValue(context.Background(), "config", cfg)
+}
+
+func TestBuildPreview(t *testing.T) {
+	t.Run("Current layout", func(t *testing.T) {
+		ctx := setupPreview(t, "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+
+		preview, err := BuildPreview(ctx, "", "", "")
+		require.NoError(t, err)
+		assert.False(t, preview.HasIssues())
+		assert.Equal(t, []PreviewEntry{
+			{Manifest: "workload", Service: "eks", Path: "111111111111/us-east-1/eks"},
+			{Manifest: "workload", Service: "vpc", Path: "111111111111/us-east-1/vpc"},
+		}, preview.Entries)
+
+		var buf bytes.Buffer
+		require.NoError(t, WritePreview(&buf, preview, utils.OutputTable))
+		assert.Equal(t, `terragrunt
+└── 111111111111
+    └── us-east-1
+        ├── eks  ← workload/eks
+        └── vpc  ← workload/vpc
+
+2 services, 0 colliding, 0 moving, 0 failed
+`, buf.String())
+	})
+
+	t.Run("Candidate strategy moves services", func(t *testing.T) {
+		ctx := setupPreview(t, "{{ var.account_id }}/{{ var.region }}/{{ var.service }}")
+
+		preview, err := BuildPreview(ctx, "", "tier=network", "by-group")
+		require.NoError(t, err)
+		require.Len(t, preview.Entries, 1)
+		assert.True(t, preview.Entries[0].Moved)
+		assert.Equal(t, "network/vpc", preview.Entries[0].Path)
+		assert.Equal(t, "111111111111/us-east-1/vpc", preview.Entries[0].CurrentPath)
+	})
+
+	t.Run("Collisions", func(t *testing.T) {
+		ctx := setupPreview(t, "{{ var.service }}")
+
+		preview, err := BuildPreview(ctx, "", "", "flat")
+		require.NoError(t, err)
+		assert.True(t, preview.HasIssues())
+		assert.Equal(t, []string{"workload/vpc"}, preview.Entries[0].Collisions)
+		assert.Equal(t, []string{"workload/eks"}, preview.Entries[1].Collisions)
+
+		var buf bytes.Buffer
+		require.NoError(t, WritePreview(&buf, preview, utils.OutputTable))
+		assert.Contains(t, buf.String(), "111111111111  ← workload/eks, workload/vpc [")
+		assert.Contains(t, buf.String(), "⚠️  collision]")
+	})
+
+	t.Run("Paths outside the output root", func(t *testing.T) {
+		ctx := setupPreview(t, "{{ var.service }}")
+
+		preview, err := BuildPreview(ctx, "", "", "escape")
+		require.NoError(t, err)
+		assert.True(t, preview.HasIssues())
+		assert.Equal(t, `invalid target path "../eks" for service eks: segment ".." escapes the output folder`, preview.Entries[0].Error)
+
+		var buf bytes.Buffer
+		require.NoError(t, WritePreview(&buf, preview, utils.OutputTable))
+		assert.Contains(t, buf.String(), "❌ services that failed to resolve:")
+	})
+
+	t.Run("Unknown candidate", func(t *testing.T) {
+		ctx := setupPreview(t, "{{ var.service }}")
+
+		_, err := BuildPreview(ctx, "", "", "missing")
+		assert.ErrorContains(t, err, "strategy missing does not exist")
+	})
+}
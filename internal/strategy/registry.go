@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+// List returns the named strategies found in the strategies folder. The
+// strategy selected in the .skiff file is flagged as the default.
+func List(ctx context.Context) ([]Strategy, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := cfg.StrategyNames()
+	if err != nil {
+		return nil, err
+	}
+
+	strategies := make([]Strategy, 0, len(names))
+	for _, name := range names {
+		s, err := cfg.LoadStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := cfg.StrategyFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		strategies = append(strategies, Strategy{
+			Name:        name,
+			Description: s.Description,
+			Path:        path,
+			Default:     name == cfg.Strategy.Name,
+		})
+	}
+	return strategies, nil
+}
+
+// WriteList writes the named strategies to w in the requested format.
+func WriteList(ctx context.Context, w io.Writer, format string) error {
+	strategies, err := List(ctx)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteOutput(w, format, strategies, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tDEFAULT\tDESCRIPTION\tPATH")
+		for _, s := range strategies {
+			def := ""
+			if s.Default {
+				def = "*"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Name, def, s.Description, s.Path)
+		}
+		return tw.Flush()
+	})
+}
+
+// Show writes the named strategy, or the one configured in the .skiff file
+// when name is empty, including its path template.
+func Show(ctx context.Context, w io.Writer, name, format string) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s, err := cfg.ResolveStrategy(name)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteOutput(w, format, s, func(w io.Writer) error {
+		if s.Name != "" {
+			fmt.Fprintf(w, "Name:        %s\n", s.Name)
+		}
+		fmt.Fprintf(w, "Description: %s\n", s.Description)
+		fmt.Fprintf(w, "Template:\n%s\n", s.Template)
+		if len(s.Levels) == 0 {
+			return nil
+		}
+
+		fmt.Fprintln(w, "Levels:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, level := range s.Levels {
+			path := level.Path
+			if path == "" {
+				path = "."
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", level.Name, path, level.FileName(), level.Template)
+		}
+		return tw.Flush()
+	})
+}
@@ -2,12 +2,17 @@ package strategy
 
 import (
 	"context"
+	"fmt"
+	"maps"
 	"path/filepath"
+	"reflect"
+	"slices"
 
 	"github.com/nyambati/skiff/internal/catalog"
 	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
 	"github.com/nyambati/skiff/internal/manifest"
-	"github.com/nyambati/skiff/internal/utils"
+	"github.com/nyambati/skiff/internal/selector"
 )
 
 var defaultTemplate = "terragrunt.default.tmpl"
@@ -19,13 +24,13 @@ var defaultTemplate = "terragrunt.default.tmpl"
 //   - manifests: a slice of pointers to Manifest, representing the account manifests
 //     to be processed
 //   - catalog: a pointer to Manifest, representing the service catalog
-//   - labels: a comma-separated string of key-value pairs, used to filter the services
+//   - labels: a label selector (see selector.Parse), used to filter the services
 //     to be processed
 //
 // The function iterates over the provided manifests and their services, and applies
 // the following steps:
 //
-//   - For each service, it checks if the service has the specified labels. If not,
+//   - For each service, it checks if the service labels match the selector. If not,
 //     it skips the service
 //   - For each service, it retrieves the service type definition from the service catalog
 //     and builds a strategy context
@@ -36,17 +41,48 @@ var defaultTemplate = "terragrunt.default.tmpl"
 //     does not specify a template
 //   - For each service, it appends a new Config to the renderConfigs slice, with the
 //     template path, target folder, and service data
+//   - For each service, it appends a Config for every strategy level, such as a
+//     root or account file, unless the same level file has already been added
+//   - For each service, it marks the Config protected when the service or its
+//     type is protected or its labels match a protected selector of .skiff
+//   - For each service, it merges the run settings of its type over those of
+//     .skiff
 //
-// The function returns a pointer to the renderConfigs slice.
-func Execute(ctx context.Context, manifests []*manifest.Manifest, catalog *catalog.Catalog, labels string) *RenderConfig {
-	cfg, ok := ctx.Value(config.ContextKey).(*config.Config)
-	if !ok {
-		return nil
+// The function returns a pointer to the renderConfigs slice, or an error if the
+// labels selector or a protected selector cannot be parsed or two services
+// resolve to the same target folder or state key.
+func Execute(ctx context.Context, manifests []*manifest.Manifest, catalog *catalog.Catalog, labels string) (*RenderConfig, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
+
+	labelSelector, err := selector.Parse(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	protected := make([]*selector.Selector, 0, len(cfg.Protected))
+	for _, input := range cfg.Protected {
+		if input == "" {
+			continue
+		}
+		protectedSelector, err := selector.Parse(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protected selector %q: %w", input, err)
+		}
+		protected = append(protected, protectedSelector)
+	}
+
+	if err := CheckCollisions(manifests); err != nil {
+		return nil, err
+	}
+
 	renderConfigs := make(RenderConfig, 0, len(manifests))
 	for _, m := range manifests {
-		for _, svc := range m.Services {
-			if labels != "" && !utils.HasLabels(svc.Labels, utils.ParseKeyValueFlag(labels)) {
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			svc := m.Services[name]
+			if !labelSelector.Matches(svc.Labels) {
 				continue
 			}
 
@@ -59,10 +95,104 @@ func Execute(ctx context.Context, manifests []*manifest.Manifest, catalog *catal
 
 			renderConfigs = append(renderConfigs, Config{
 				Template:     templatePath,
-				TargetFolder: utils.SanitizePath(filepath.Join(cfg.Terragrunt, svc.ResolvedTargetPath)),
+				TargetFolder: filepath.Join(cfg.Terragrunt, svc.ResolvedTargetPath),
+				File:         config.TerragruntFile,
 				Context:      &svc.TemplateContext,
+				Manifest:     m.Name,
+				Service:      name,
+				Metadata:     m.Metadata,
+				Protected: svc.Protected || svc.ResolvedType.Protected || slices.ContainsFunc(protected, func(s *selector.Selector) bool {
+					return s.Matches(svc.Labels)
+				}),
+				Run: cfg.Run.Merge(svc.ResolvedType.Run),
 			})
+
+			renderConfigs = appendLevels(renderConfigs, cfg, svc.ResolvedLevels, m, name)
+		}
+	}
+	return &renderConfigs, nil
+}
+
+// appendLevels adds the level files of a service to configs. Level files are
+// shared by every service below their folder, a level already added with the
+// same context is skipped. Differing contexts are kept, the renderer rejects
+// them when they produce different files.
+func appendLevels(configs RenderConfig, cfg *config.Config, levels []catalog.ResolvedLevel, m *manifest.Manifest, service string) RenderConfig {
+	for _, level := range levels {
+		levelConfig := Config{
+			Template:     filepath.Join(cfg.Templates, level.Template),
+			TargetFolder: filepath.Join(cfg.Terragrunt, level.Path),
+			File:         level.File,
+			Level:        level.Name,
+			Context:      &level.Context,
+			Manifest:     m.Name,
+			Service:      service,
+			Metadata:     m.Metadata,
+		}
+
+		if !slices.ContainsFunc(configs, func(c Config) bool {
+			return c.OutputPath() == levelConfig.OutputPath() && reflect.DeepEqual(c.Context, levelConfig.Context)
+		}) {
+			configs = append(configs, levelConfig)
+		}
+	}
+	return configs
+}
+
+// OutputPath returns the path of the file the config is rendered to.
+func (c Config) OutputPath() string {
+	return filepath.Join(c.TargetFolder, c.File)
+}
+
+// CheckCollisions makes sure no two services, selected or not, render into the
+// same folder or share a state key, and that no level file overwrites the file
+// of a service. Generating one of them would silently overwrite the other.
+// The manifests are expected to be resolved.
+func CheckCollisions(manifests []*manifest.Manifest) error {
+	owners := map[string]string{}
+	for _, m := range manifests {
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			targetPath := m.Services[name].ResolvedTargetPath
+			if targetPath == "" {
+				continue
+			}
+
+			id := m.Name + "/" + name
+			if owner, exists := owners[targetPath]; exists {
+				return skiff.NewTargetPathCollisionError(targetPath, owner, id)
+			}
+			owners[targetPath] = id
+		}
+	}
+
+	stateKeys := map[string]string{}
+	for _, m := range manifests {
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			backend := m.Services[name].ResolvedBackend
+			if backend == nil {
+				continue
+			}
+
+			id := m.Name + "/" + name
+			stateKey := backend.Location() + "\x00" + backend.Key
+			if owner, exists := stateKeys[stateKey]; exists {
+				return skiff.NewStateKeyCollisionError(backend.Key, backend.Location(), owner, id)
+			}
+			stateKeys[stateKey] = id
+		}
+	}
+
+	for _, m := range manifests {
+		for _, name := range slices.Sorted(maps.Keys(m.Services)) {
+			for _, level := range m.Services[name].ResolvedLevels {
+				if owner, exists := owners[level.Path]; exists && level.File == config.TerragruntFile {
+					return fmt.Errorf(
+						"level %s of %s/%s writes %s into the folder of service %s",
+						level.Name, m.Name, name, config.TerragruntFile, owner,
+					)
+				}
+			}
 		}
 	}
-	return &renderConfigs
+	return nil
 }
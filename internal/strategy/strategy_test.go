@@ -10,6 +10,7 @@ import (
 	"github.com/nyambati/skiff/internal/manifest"
 	"github.com/nyambati/skiff/internal/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var skiffConfig *config.Config
@@ -50,6 +51,8 @@ func TestExecute(t *testing.T) {
 			expectedConfig: &RenderConfig{{
 				Template:     filepath.Join(skiffConfig.Path.Templates, defaultTemplate),
 				TargetFolder: filepath.Join(skiffConfig.Path.Terragrunt, "test/path"),
+				File:         config.TerragruntFile,
+				Service:      "test-service",
 				Context: &types.TemplateContext{
 					"name": "test-service",
 				},
@@ -78,6 +81,8 @@ func TestExecute(t *testing.T) {
 			expectedConfig: &RenderConfig{{
 				Template:     filepath.Join(skiffConfig.Path.Templates, "custom.tmpl"),
 				TargetFolder: filepath.Join(skiffConfig.Path.Terragrunt, "custom/path"),
+				File:         config.TerragruntFile,
+				Service:      "custom-service",
 				Context: &types.TemplateContext{
 					"name": "custom-service",
 				},
@@ -105,13 +110,193 @@ func TestExecute(t *testing.T) {
 			labels:         "env=dev",
 			expectedConfig: &RenderConfig{},
 		},
+		{
+			name: "Service matching set based selector",
+			manifests: []*manifest.Manifest{{
+				Services: map[string]catalog.Service{
+					"staging-service": {
+						Labels: map[string]any{
+							"env":        "staging",
+							"account_id": 123456789012,
+						},
+						ResolvedType:       &catalog.ServiceType{},
+						ResolvedTargetPath: "staging/path",
+						TemplateContext: types.TemplateContext{
+							"name": "staging-service",
+						},
+					},
+				},
+			}},
+			catalog: &catalog.Catalog{},
+			labels:  "env in (dev,staging),account_id=123456789012,!deprecated",
+			expectedConfig: &RenderConfig{{
+				Template:     filepath.Join(skiffConfig.Path.Templates, defaultTemplate),
+				TargetFolder: filepath.Join(skiffConfig.Path.Terragrunt, "staging/path"),
+				File:         config.TerragruntFile,
+				Service:      "staging-service",
+				Context: &types.TemplateContext{
+					"name": "staging-service",
+				},
+			}},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), "config", skiffConfig)
-			result := Execute(ctx, tc.manifests, tc.catalog, tc.labels)
+			result, err := Execute(ctx, tc.manifests, tc.catalog, tc.labels)
+			require.NoError(t, err)
 			assert.Equal(t, tc.expectedConfig, result)
 		})
 	}
 }
+
+func TestExecuteInvalidSelector(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "config", &config.Config{})
+	_, err := Execute(ctx, nil, &catalog.Catalog{}, "env in prod")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid label selector")
+}
+
+func TestExecuteTargetPathCollision(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "config", &config.Config{})
+	manifests := []*manifest.Manifest{
+		{
+			Name: "account-a",
+			Services: map[string]catalog.Service{
+				"vpc": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "shared/vpc"},
+			},
+		},
+		{
+			Name: "account-b",
+			Services: map[string]catalog.Service{
+				"vpc": {
+					Labels:             map[string]any{"env": "dev"},
+					ResolvedType:       &catalog.ServiceType{},
+					ResolvedTargetPath: "shared/vpc",
+				},
+			},
+		},
+	}
+
+	// unselected services still collide, generating one overwrites the other
+	_, err := Execute(ctx, manifests, &catalog.Catalog{}, "env=dev")
+	require.Error(t, err)
+	assert.Equal(
+		t,
+		"services account-a/vpc and account-b/vpc resolve to the same target path shared/vpc, adjust the strategy or the service labels",
+		err.Error(),
+	)
+}
+
+func TestExecuteLevels(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "config", &config.Config{
+		Path: config.Path{Templates: "templates", Terragrunt: "terragrunt"},
+	})
+
+	account := func(region string) []catalog.ResolvedLevel {
+		return []catalog.ResolvedLevel{{
+			Name:     "account",
+			Path:     "123",
+			Template: "account.hcl.tmpl",
+			File:     "account.hcl",
+			Context:  types.TemplateContext{"account_id": "123", "level": "account", "region": region},
+		}}
+	}
+
+	manifests := []*manifest.Manifest{{
+		Name: "workload",
+		Services: map[string]catalog.Service{
+			"eks": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "123/eu-west-1/eks", ResolvedLevels: account("eu-west-1")},
+			"rds": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "123/us-east-1/rds", ResolvedLevels: account("us-east-1")},
+			"vpc": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "123/us-east-1/vpc", ResolvedLevels: account("us-east-1")},
+		},
+	}}
+
+	result, err := Execute(ctx, manifests, &catalog.Catalog{}, "")
+	require.NoError(t, err)
+
+	var outputs []string
+	for _, c := range *result {
+		outputs = append(outputs, c.Level+":"+c.OutputPath())
+	}
+
+	// the account file is added once per distinct context, the renderer
+	// decides whether both contexts produce the same file
+	assert.Equal(t, []string{
+		":terragrunt/123/eu-west-1/eks/terragrunt.hcl",
+		"account:terragrunt/123/account.hcl",
+		":terragrunt/123/us-east-1/rds/terragrunt.hcl",
+		"account:terragrunt/123/account.hcl",
+		":terragrunt/123/us-east-1/vpc/terragrunt.hcl",
+	}, outputs)
+
+	t.Run("Level overwriting a service", func(t *testing.T) {
+		manifests[0].Services["vpc"] = catalog.Service{
+			ResolvedType:       &catalog.ServiceType{},
+			ResolvedTargetPath: "123/us-east-1/vpc",
+			ResolvedLevels: []catalog.ResolvedLevel{{
+				Name: "region",
+				Path: "123/us-east-1/rds",
+				File: config.TerragruntFile,
+			}},
+		}
+
+		_, err := Execute(ctx, manifests, &catalog.Catalog{}, "")
+		assert.EqualError(t, err, "level region of workload/vpc writes terragrunt.hcl into the folder of service workload/rds")
+	})
+}
+
+func TestExecuteStateKeyCollision(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "config", &config.Config{})
+	backend := func(bucket, key string) *config.Backend {
+		return &config.Backend{Type: config.BackendS3, Key: key, Config: map[string]any{"bucket": bucket}}
+	}
+
+	manifests := []*manifest.Manifest{{
+		Name: "workload",
+		Services: map[string]catalog.Service{
+			"eks": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "eks", ResolvedBackend: backend("state", "shared.tfstate")},
+			"rds": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "rds", ResolvedBackend: backend("other", "shared.tfstate")},
+		},
+	}}
+
+	// the same key in another bucket is fine
+	_, err := Execute(ctx, manifests, &catalog.Catalog{}, "")
+	require.NoError(t, err)
+
+	manifests[0].Services["vpc"] = catalog.Service{
+		ResolvedType:       &catalog.ServiceType{},
+		ResolvedTargetPath: "vpc",
+		ResolvedBackend:    backend("state", "shared.tfstate"),
+	}
+
+	_, err = Execute(ctx, manifests, &catalog.Catalog{}, "")
+	assert.EqualError(t, err, "services workload/eks and workload/vpc share the state key shared.tfstate in s3/state, adjust the backend key pattern")
+}
+
+func TestExecuteProtected(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "config", &config.Config{Protected: []string{"tier=data"}})
+	manifests := []*manifest.Manifest{{
+		Name: "workload",
+		Services: map[string]catalog.Service{
+			"dns": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "dns", Protected: true},
+			"eks": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "eks"},
+			"rds": {ResolvedType: &catalog.ServiceType{}, ResolvedTargetPath: "rds", Labels: map[string]any{"tier": "data"}},
+			"vpc": {ResolvedType: &catalog.ServiceType{Protected: true}, ResolvedTargetPath: "vpc"},
+		},
+	}}
+
+	result, err := Execute(ctx, manifests, &catalog.Catalog{}, "")
+	require.NoError(t, err)
+
+	protected := map[string]bool{}
+	for _, c := range *result {
+		protected[c.Service] = c.Protected
+	}
+	assert.Equal(t, map[string]bool{"dns": true, "eks": false, "rds": true, "vpc": true}, protected)
+
+	ctx = context.WithValue(context.Background(), "config", &config.Config{Protected: []string{"tier in data"}})
+	_, err = Execute(ctx, manifests, &catalog.Catalog{}, "")
+	assert.ErrorContains(t, err, `invalid protected selector "tier in data"`)
+}
@@ -1,6 +1,7 @@
 package strategy
 
 import (
+	"github.com/nyambati/skiff/internal/config"
 	"github.com/nyambati/skiff/internal/types"
 )
 
@@ -9,12 +10,85 @@ type (
 		Template     string
 		Context      *types.TemplateContext
 		TargetFolder string
+		// File is the name of the rendered file inside TargetFolder.
+		File string
+		// Level is the strategy level the config renders, empty for services.
+		Level string
+		// Manifest and Service name the service the config renders, or the
+		// first service below the level.
+		Manifest string
+		Service  string
+		// Metadata is the metadata of the manifest.
+		Metadata types.Metadata
+		// Protected is set for services marked protected, directly, through
+		// their catalog type or by a protected selector of .skiff.
+		Protected bool
+		// Run holds the run settings of .skiff with those of the service type
+		// applied on top.
+		Run *config.RunSettings
 	}
 
+	// Strategy describes a named strategy file in the strategies folder.
 	Strategy struct {
-		Description string `yaml:"description"`
-		Path        string `yaml:"path"`
+		Name        string `json:"name" yaml:"name"`
+		Description string `json:"description" yaml:"description"`
+		Path        string `json:"path" yaml:"path"`
+		Default     bool   `json:"default" yaml:"default"`
 	}
 
 	RenderConfig []Config
+
+	// Preview is the resolved layout of the selected services, relative to
+	// the terragrunt output folder Root.
+	Preview struct {
+		Root    string         `json:"root" yaml:"root"`
+		Entries []PreviewEntry `json:"services" yaml:"services"`
+	}
+
+	// PreviewEntry is the resolved target path of a single service and the
+	// problems found with it.
+	PreviewEntry struct {
+		Manifest    string   `json:"manifest" yaml:"manifest"`
+		Service     string   `json:"service" yaml:"service"`
+		Path        string   `json:"path" yaml:"path"`
+		CurrentPath string   `json:"current_path,omitempty" yaml:"current_path,omitempty"`
+		Moved       bool     `json:"moved" yaml:"moved"`
+		Collisions  []string `json:"collisions,omitempty" yaml:"collisions,omitempty"`
+		Error       string   `json:"error,omitempty" yaml:"error,omitempty"`
+	}
+
+	// MoveStatus is the state of a single folder move in a Migration.
+	MoveStatus string
+
+	// Migration moves the generated folders of the selected services from the
+	// layout of a previous strategy to the current one, below Root.
+	Migration struct {
+		Root  string `json:"root" yaml:"root"`
+		Moves []Move `json:"services" yaml:"services"`
+	}
+
+	// Move is the old and new target path of a single service.
+	Move struct {
+		Manifest string     `json:"manifest" yaml:"manifest"`
+		Service  string     `json:"service" yaml:"service"`
+		From     string     `json:"from" yaml:"from"`
+		To       string     `json:"to" yaml:"to"`
+		FromKey  string     `json:"from_state_key" yaml:"from_state_key"`
+		ToKey    string     `json:"to_state_key" yaml:"to_state_key"`
+		Status   MoveStatus `json:"status" yaml:"status"`
+		Reason   string     `json:"reason,omitempty" yaml:"reason,omitempty"`
+	}
+)
+
+const (
+	// MovePending is a generated folder that moves when the migration is applied.
+	MovePending MoveStatus = "pending"
+	// MoveDone is a folder that has been moved.
+	MoveDone MoveStatus = "moved"
+	// MoveUnchanged is a service whose folder is the same in both layouts.
+	MoveUnchanged MoveStatus = "unchanged"
+	// MoveNotGenerated is a service whose old folder has not been generated.
+	MoveNotGenerated MoveStatus = "not-generated"
+	// MoveConflict is a folder that cannot be moved, see Move.Reason.
+	MoveConflict MoveStatus = "conflict"
 )
@@ -0,0 +1,217 @@
+package template
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ctyValue encodes a decoded YAML value to cty. Sequences whose elements
+// share a type become lists and the others tuples, mappings whose values
+// share a type become maps and the others objects, nil becomes null. path
+// names the value in errors, for example inputs.subnets[0].
+func ctyValue(path string, value interface{}) (cty.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	case cty.Value:
+		return v, nil
+	case types.Expression:
+		return cty.NilVal, skiff.NewInvalidValueError(path, fmt.Sprintf("expression %q cannot be used as a value here", string(v)))
+	case string:
+		warnLegacyExpression(path, v)
+		return cty.StringVal(v), nil
+	case bool:
+		return cty.BoolVal(v), nil
+	case map[string]interface{}:
+		return ctyMap(path, v)
+	case []interface{}:
+		return ctyList(path, v)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cty.NumberIntVal(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cty.NumberUIntVal(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return cty.NumberFloatVal(rv.Float()), nil
+	case reflect.String:
+		return cty.StringVal(rv.String()), nil
+	case reflect.Bool:
+		return cty.BoolVal(rv.Bool()), nil
+
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return cty.NullVal(cty.DynamicPseudoType), nil
+		}
+		return ctyValue(path, rv.Elem().Interface())
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return cty.NilVal, skiff.NewInvalidValueError(path, fmt.Sprintf("unsupported map key type %s", rv.Type().Key()))
+		}
+		values := make(map[string]interface{}, rv.Len())
+		for _, key := range rv.MapKeys() {
+			values[key.String()] = rv.MapIndex(key).Interface()
+		}
+		return ctyMap(path, values)
+
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, rv.Index(i).Interface())
+		}
+		return ctyList(path, values)
+	}
+
+	return cty.NilVal, skiff.NewInvalidValueError(path, fmt.Sprintf("unsupported value of type %T", value))
+}
+
+func ctyMap(path string, values map[string]interface{}) (cty.Value, error) {
+	if len(values) == 0 {
+		return cty.EmptyObjectVal, nil
+	}
+
+	attrs := make(map[string]cty.Value, len(values))
+	for key, item := range values {
+		encoded, err := ctyValue(childPath(path, key), item)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		attrs[key] = encoded
+	}
+
+	if sameType(slices.Collect(maps.Values(attrs))) {
+		return cty.MapVal(attrs), nil
+	}
+	return cty.ObjectVal(attrs), nil
+}
+
+func ctyList(path string, values []interface{}) (cty.Value, error) {
+	if len(values) == 0 {
+		return cty.EmptyTupleVal, nil
+	}
+
+	items := make([]cty.Value, 0, len(values))
+	for i, item := range values {
+		encoded, err := ctyValue(fmt.Sprintf("%s[%d]", path, i), item)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		items = append(items, encoded)
+	}
+
+	if sameType(items) {
+		return cty.ListVal(items), nil
+	}
+	return cty.TupleVal(items), nil
+}
+
+// sameType reports whether values share a known type, so they can form a list
+// or a map. Nulls of an unknown type only fit tuples and objects.
+func sameType(values []cty.Value) bool {
+	for _, value := range values {
+		if value.Type() == cty.DynamicPseudoType || !value.Type().Equals(values[0].Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// warnLegacyExpression warns about a string written with the __ prefix that
+// used to mark expressions, such as "__dependency.vpc.outputs.id". It is now
+// written as a quoted string, the expression needs !expr.
+func warnLegacyExpression(path, value string) {
+	expr, found := strings.CutPrefix(value, "__")
+	if !found {
+		return
+	}
+	if _, diags := hclsyntax.ParseTraversalAbs([]byte(expr), "", hcl.InitialPos); diags.HasErrors() {
+		return
+	}
+	logrus.Warnf("%s: %q is written as a string, the __ prefix no longer marks expressions, use !expr %s\n", path, value, expr)
+}
+
+func childPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// valueTokens returns the tokens of value, writing expressions as they are
+// and everything else as literals. Object keys are sorted.
+func valueTokens(path string, value interface{}) (hclwrite.Tokens, error) {
+	if !types.ContainsExpression(value) {
+		encoded, err := ctyValue(path, value)
+		if err != nil {
+			return nil, err
+		}
+		return hclwrite.TokensForValue(encoded), nil
+	}
+
+	switch v := value.(type) {
+	case types.Expression:
+		return expressionTokens(v), nil
+
+	case map[string]interface{}:
+		attrs := make([]hclwrite.ObjectAttrTokens, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			tokens, err := valueTokens(childPath(path, key), v[key])
+			if err != nil {
+				return nil, err
+			}
+			name := hclwrite.TokensForValue(cty.StringVal(key))
+			if hclsyntax.ValidIdentifier(key) {
+				name = hclwrite.TokensForIdentifier(key)
+			}
+			attrs = append(attrs, hclwrite.ObjectAttrTokens{Name: name, Value: tokens})
+		}
+		return hclwrite.TokensForObject(attrs), nil
+
+	default:
+		list, _ := v.([]interface{})
+		items := make([]hclwrite.Tokens, 0, len(list))
+		for i, item := range list {
+			tokens, err := valueTokens(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, tokens)
+		}
+		return hclwrite.TokensForTuple(items), nil
+	}
+}
+
+// expressionTokens lexes an expression into tokens, keeping its spacing.
+// Expressions are validated when they are decoded.
+func expressionTokens(expr types.Expression) hclwrite.Tokens {
+	lexed, _ := hclsyntax.LexExpression([]byte(expr), "", hcl.InitialPos)
+
+	tokens := make(hclwrite.Tokens, 0, len(lexed))
+	end := 0
+	for _, token := range lexed {
+		if token.Type == hclsyntax.TokenEOF {
+			break
+		}
+		tokens = append(tokens, &hclwrite.Token{
+			Type:         token.Type,
+			Bytes:        token.Bytes,
+			SpacesBefore: token.Range.Start.Byte - end,
+		})
+		end = token.Range.End.Byte
+	}
+	return tokens
+}
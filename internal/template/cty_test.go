@@ -0,0 +1,149 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCtyValue(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected cty.Value
+	}{
+		{
+			name:     "Null",
+			value:    nil,
+			expected: cty.NullVal(cty.DynamicPseudoType),
+		},
+		{
+			name:     "Unsigned integer",
+			value:    uint64(42),
+			expected: cty.NumberUIntVal(42),
+		},
+		{
+			name:     "List of one type",
+			value:    []interface{}{"a", "b"},
+			expected: cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		},
+		{
+			name:     "Mixed list",
+			value:    []interface{}{"a", 1, true},
+			expected: cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1), cty.True}),
+		},
+		{
+			name:  "Nested lists",
+			value: []interface{}{[]interface{}{1, 2}, []interface{}{3}},
+			expected: cty.ListVal([]cty.Value{
+				cty.ListVal([]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2)}),
+				cty.ListVal([]cty.Value{cty.NumberIntVal(3)}),
+			}),
+		},
+		{
+			name: "Objects with optional fields",
+			value: []interface{}{
+				map[string]interface{}{"cidr": "10.0.1.0/24", "public": true},
+				map[string]interface{}{"cidr": "10.0.2.0/24"},
+			},
+			expected: cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"cidr": cty.StringVal("10.0.1.0/24"), "public": cty.True}),
+				cty.MapVal(map[string]cty.Value{"cidr": cty.StringVal("10.0.2.0/24")}),
+			}),
+		},
+		{
+			name:  "Map of lists",
+			value: map[string]interface{}{"a": []interface{}{"x"}, "b": []interface{}{"y"}},
+			expected: cty.MapVal(map[string]cty.Value{
+				"a": cty.ListVal([]cty.Value{cty.StringVal("x")}),
+				"b": cty.ListVal([]cty.Value{cty.StringVal("y")}),
+			}),
+		},
+		{
+			name:     "Map with a null",
+			value:    map[string]interface{}{"name": "vpc", "cidr": nil},
+			expected: cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("vpc"), "cidr": cty.NullVal(cty.DynamicPseudoType)}),
+		},
+		{
+			name:     "Typed map and slice",
+			value:    map[string][]string{"azs": {"a"}},
+			expected: cty.MapVal(map[string]cty.Value{"azs": cty.ListVal([]cty.Value{cty.StringVal("a")})}),
+		},
+		{
+			name:     "Empty list and map",
+			value:    []interface{}{[]interface{}{}, map[string]interface{}{}},
+			expected: cty.TupleVal([]cty.Value{cty.EmptyTupleVal, cty.EmptyObjectVal}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := ctyValue("inputs", tc.value)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.RawEquals(value), "expected %#v, got %#v", tc.expected, value)
+		})
+	}
+}
+
+func TestCtyValueUnsupported(t *testing.T) {
+	_, err := ctyValue("inputs", map[string]interface{}{
+		"subnets": []interface{}{"10.0.1.0/24", make(chan int)},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "inputs.subnets[1]: unsupported value of type chan int")
+
+	_, err = ctyValue("inputs", map[int]string{1: "a"})
+	assert.Error(t, err)
+}
+
+func TestLegacyExpressionWarning(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+
+	out, err := RenderToHCL(map[string]interface{}{
+		"inputs": map[string]interface{}{
+			"vpc_id": "__dependency.vpc.outputs.id",
+			"name":   "__not an expression",
+			"plain":  "dependency.vpc.outputs.id",
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, out, `vpc_id = "__dependency.vpc.outputs.id"`)
+
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Equal(t, `inputs.vpc_id: "__dependency.vpc.outputs.id" is written as a string, the __ prefix no longer marks expressions, use !expr dependency.vpc.outputs.id`+"\n", hook.LastEntry().Message)
+}
+
+func TestRenderToHCLValues(t *testing.T) {
+	rendered, err := RenderToHCL(map[string]interface{}{
+		"inputs": map[string]interface{}{
+			"clear":   nil,
+			"matrix":  []interface{}{[]interface{}{1, 2}, []interface{}{"a"}},
+			"mixed":   []interface{}{"a", 1, false},
+			"size":    uint(3),
+			"subnets": []interface{}{map[string]interface{}{"cidr": "10.0.1.0/24", "public": true}, map[string]interface{}{"cidr": "10.0.2.0/24"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `inputs = {
+  clear  = null
+  matrix = [[1, 2], ["a"]]
+  mixed  = ["a", 1, false]
+  size   = 3
+  subnets = [{
+    cidr   = "10.0.1.0/24"
+    public = true
+    }, {
+    cidr = "10.0.2.0/24"
+  }]
+}
+`, rendered)
+
+	_, err = RenderToHCL(map[string]interface{}{"inputs": map[string]interface{}{"bad": func() {}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/strategy"
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// testdataFolder holds the fixtures of `skiff template test`, below the
+	// templates folder.
+	testdataFolder = "testdata"
+	goldenExt      = ".golden"
+
+	TestPassed  = "passed"
+	TestFailed  = "failed"
+	TestUpdated = "updated"
+)
+
+type (
+	// Fixture renders Template, relative to the templates folder, with
+	// Context as if BuildTemplateContext had built it for a service of
+	// Manifest with Metadata. Path is the folder of the rendered file
+	// relative to the terragrunt folder, used by relpath.
+	Fixture struct {
+		Template string         `yaml:"template"`
+		Manifest string         `yaml:"manifest,omitempty"`
+		Metadata types.Metadata `yaml:"metadata,omitempty"`
+		Path     string         `yaml:"path,omitempty"`
+		Context  types.Values   `yaml:"context"`
+	}
+
+	// TestResult is the outcome of rendering one fixture. Diff holds the
+	// unified diff from the golden file to the output when they differ.
+	TestResult struct {
+		Fixture  string `json:"fixture" yaml:"fixture"`
+		Template string `json:"template,omitempty" yaml:"template,omitempty"`
+		Status   string `json:"status" yaml:"status"`
+		Diff     string `json:"diff,omitempty" yaml:"diff,omitempty"`
+		Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+	}
+)
+
+// RunTests renders every fixture of templates/testdata, a *.yaml file, and
+// compares the output with the golden file next to it, the fixture name with
+// the .golden extension. With update the golden files are written instead.
+// Results are sorted by fixture.
+func RunTests(ctx context.Context, update bool) ([]TestResult, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures, err := filepath.Glob(filepath.Join(cfg.Templates, testdataFolder, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := loadTemplates(cfg.Templates, (&templateFuncs{}).funcMap())
+	if err != nil {
+		return nil, err
+	}
+
+	// the catalog function needs the catalog, fixtures may go without it
+	var serviceTypes *catalog.Catalog
+	if c := new(catalog.Catalog); c.Read(filepath.Join(cfg.Manifests, config.CatalogFile)) == nil {
+		serviceTypes = c
+	}
+
+	results := make([]TestResult, 0, len(fixtures))
+	for _, file := range fixtures {
+		results = append(results, testFixture(cfg, serviceTypes, templates, file, update))
+	}
+	return results, nil
+}
+
+// testFixture renders the fixture at file and compares the output with its
+// golden file, or writes the golden file with update.
+func testFixture(cfg *config.Config, serviceTypes *catalog.Catalog, templates *template.Template, file string, update bool) TestResult {
+	result := TestResult{Fixture: filepath.Base(file), Status: TestFailed}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	fixture, err := utils.FromYAMLStrict[Fixture](data)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid fixture: %s", err)
+		return result
+	}
+	if fixture.Template == "" {
+		result.Error = "invalid fixture: template is required"
+		return result
+	}
+	result.Template = fixture.Template
+
+	context := types.TemplateContext(fixture.Context)
+	service, _ := context[config.ServiceKey].(string)
+	content, err := renderConfig(strategy.Config{
+		Template:     filepath.Join(cfg.Templates, fixture.Template),
+		TargetFolder: filepath.Join(cfg.Terragrunt, fixture.Path),
+		File:         config.TerragruntFile,
+		Context:      &context,
+		Manifest:     fixture.Manifest,
+		Service:      service,
+		Metadata:     fixture.Metadata,
+	}, cfg, serviceTypes, templates)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	golden := strings.TrimSuffix(file, filepath.Ext(file)) + goldenExt
+	if update {
+		if err := utils.WriteFileAtomic(golden, content, 0644); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Status = TestUpdated
+		return result
+	}
+
+	expected, err := os.ReadFile(golden)
+	if errors.Is(err, fs.ErrNotExist) {
+		result.Error = fmt.Sprintf("%s does not exist, run with --update to create it", filepath.Base(golden))
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if string(expected) != string(content) {
+		result.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(expected)),
+			B:        difflib.SplitLines(string(content)),
+			FromFile: filepath.Base(golden),
+			ToFile:   "rendered",
+			Context:  2,
+		})
+		result.Error = "output differs from the golden file"
+		return result
+	}
+
+	result.Status = TestPassed
+	return result
+}
+
+// WriteTestResults writes the results in format, the table listing one
+// fixture per line followed by the diffs of the failed ones.
+func WriteTestResults(w io.Writer, results []TestResult, format string) error {
+	return utils.WriteOutput(w, format, results, func(w io.Writer) error {
+		failed := 0
+		for _, result := range results {
+			switch result.Status {
+			case TestPassed:
+				fmt.Fprintf(w, "✅ %s\n", result.Fixture)
+			case TestUpdated:
+				fmt.Fprintf(w, "📝 %s updated\n", result.Fixture)
+			default:
+				failed++
+				fmt.Fprintf(w, "❌ %s", result.Fixture)
+				if result.Error != "" {
+					fmt.Fprintf(w, ": %s", result.Error)
+				}
+				fmt.Fprintln(w)
+				if result.Diff != "" {
+					fmt.Fprintln(w, result.Diff)
+				}
+			}
+		}
+		fmt.Fprintf(w, "%d fixtures, %d failed\n", len(results), failed)
+		return nil
+	})
+}
+
+// TestsFailed reports whether any fixture failed.
+func TestsFailed(results []TestResult) bool {
+	for _, result := range results {
+		if result.Status == TestFailed {
+			return true
+		}
+	}
+	return false
+}
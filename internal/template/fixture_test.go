@@ -0,0 +1,77 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTests(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/eks.tmpl": `include "root" {
+  path = "{{ relpath "root.hcl" }}"
+}
+# {{ manifest "account_id" }} {{ (catalog "vpc").version }}
+{{ service_config }}`,
+		"templates/testdata/eks.yaml": `template: eks.tmpl
+manifest: workload
+metadata:
+  account_id: "123"
+path: 123/us-east-1/eks
+context:
+  service: eks
+  body:
+    inputs:
+      cluster_name: platform
+      vpc_id: !expr dependency.vpc.outputs.vpc_id
+`,
+		"templates/testdata/invalid.yaml": "context: {}\n",
+	})
+	ctx := context.WithValue(context.Background(), "config", cfg)
+	golden := filepath.Join(cfg.Templates, "testdata", "eks.golden")
+
+	results, err := RunTests(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, TestFailed, results[0].Status)
+	assert.Equal(t, "eks.golden does not exist, run with --update to create it", results[0].Error)
+	assert.Equal(t, "invalid fixture: template is required", results[1].Error)
+	assert.True(t, TestsFailed(results))
+
+	results, err = RunTests(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, TestUpdated, results[0].Status)
+
+	content, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, `include "root" {
+  path = "../../../root.hcl"
+}
+# 123 1.0.0
+inputs = {
+  cluster_name = "platform"
+  vpc_id       = dependency.vpc.outputs.vpc_id
+}
+`, string(content))
+
+	results, err = RunTests(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, TestPassed, results[0].Status)
+
+	require.NoError(t, os.WriteFile(golden, bytes.Replace(content, []byte("platform"), []byte("old"), 1), 0644))
+	results, err = RunTests(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, TestFailed, results[0].Status)
+	assert.Contains(t, results[0].Diff, `-  cluster_name = "old"`)
+	assert.Contains(t, results[0].Diff, `+  cluster_name = "platform"`)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteTestResults(&out, results, "table"))
+	assert.Contains(t, out.String(), "❌ eks.yaml: output differs from the golden file\n")
+	assert.Contains(t, out.String(), "2 fixtures, 2 failed\n")
+}
@@ -0,0 +1,243 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/strategy"
+	"github.com/nyambati/skiff/internal/types"
+)
+
+// maxIncludeDepth bounds include_template, so templates that include each
+// other fail instead of recursing forever.
+const maxIncludeDepth = 10
+
+// templateFuncs holds the state of the functions available while rendering a
+// single file. None of the functions changes the template context.
+type templateFuncs struct {
+	cfg       strategy.Config
+	config    *config.Config
+	catalog   *catalog.Catalog
+	templates *template.Template
+
+	remoteStateRendered bool
+	depth               int
+}
+
+// funcMap returns the sprig functions plus the skiff ones:
+//
+//   - var: the template context, a copy without the terraform and body keys
+//   - terraform_attributes: the attributes of the terraform block
+//   - service_config: the dependency blocks, provider generation and inputs
+//   - remote_state: the remote_state block, empty without a backend
+//   - hcl: any value written as an HCL expression
+//   - dependency "name": the resolved dependency of the service on name
+//   - output "dependency" "key": the expression reading an output of a dependency
+//   - relpath "path": path, given relative to the terragrunt folder, relative to
+//     the rendered file
+//   - manifest ["key"]: the metadata of the manifest, or one value of it
+//   - catalog "type": the catalog entry of a service type
+//   - include_template "name": another template of the set, such as
+//     _partials/locals.tmpl, rendered with the same context
+//   - required "message" value: value, or an error with message when it is
+//     missing or empty
+func (f *templateFuncs) funcMap() template.FuncMap {
+	funcMap := sprig.TxtFuncMap()
+	funcMap[config.VarKey] = f.vars
+	funcMap[config.TerraformAttributesKey] = f.terraformAttributes
+	funcMap[config.ServiceConfigKey] = f.serviceConfig
+	funcMap[config.RemoteStateKey] = f.remoteState
+	funcMap[config.HCLKey] = hclValue
+	funcMap[config.DependencyFuncKey] = f.dependency
+	funcMap[config.OutputKey] = f.output
+	funcMap[config.RelPathKey] = f.relPath
+	funcMap[config.ManifestKey] = f.manifest
+	funcMap[config.CatalogKey] = f.catalogType
+	funcMap[config.IncludeTemplateKey] = f.includeTemplate
+	funcMap[config.RequiredKey] = required
+	return funcMap
+}
+
+// bind gives f its own copy of templates, calling the functions of f.
+func (f *templateFuncs) bind(templates *template.Template) error {
+	clone, err := templates.Clone()
+	if err != nil {
+		return err
+	}
+	f.templates = clone.Funcs(f.funcMap())
+	return nil
+}
+
+// execute renders the template file at path, a template of the set when it
+// is below the templates folder or in the library.
+func (f *templateFuncs) execute(path string) ([]byte, error) {
+	name := templateName(f.config.Templates, path)
+	tmpl := f.templates.Lookup(name)
+	if name == "" || tmpl == nil {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		if tmpl, err = f.templates.New(path).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+	}
+
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&buff, nil); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+func (f *templateFuncs) context() types.TemplateContext {
+	if f.cfg.Context == nil {
+		return types.TemplateContext{}
+	}
+	return *f.cfg.Context
+}
+
+func (f *templateFuncs) vars() types.TemplateContext {
+	vars, _ := render(f.context()).(map[string]interface{})
+	delete(vars, config.TerraformKey)
+	delete(vars, config.BodyKey)
+	return vars
+}
+
+func (f *templateFuncs) terraformAttributes() (string, error) {
+	terraform, ok := f.context()[config.TerraformKey].(map[string]interface{})
+	if !ok {
+		return "", skiff.NewInvalidValueError(config.TerraformKey, fmt.Sprintf("must be a map, got %T", f.context()[config.TerraformKey]))
+	}
+	return RenderTerraformAttrs(terraform)
+}
+
+func (f *templateFuncs) serviceConfig() (string, error) {
+	body, ok := f.context()[config.BodyKey].(map[string]interface{})
+	if !ok {
+		return "", skiff.NewInvalidValueError(config.BodyKey, fmt.Sprintf("must be a map, got %T", f.context()[config.BodyKey]))
+	}
+	return RenderToHCL(body)
+}
+
+func (f *templateFuncs) remoteState() (string, error) {
+	f.remoteStateRendered = true
+	state, ok := f.context()[config.RemoteStateKey].(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	return RenderRemoteState(state)
+}
+
+func (f *templateFuncs) dependency(name string) (map[string]interface{}, error) {
+	dependencies, _ := render(f.context()[config.DependencyKey]).([]interface{})
+	for _, raw := range dependencies {
+		if dep, ok := raw.(map[string]interface{}); ok && dep[config.ServiceKey] == name {
+			return dep, nil
+		}
+	}
+	return nil, fmt.Errorf("%s does not depend on %s", f.cfg.Service, name)
+}
+
+func (f *templateFuncs) output(dependency, key string) (types.Expression, error) {
+	if _, err := f.dependency(dependency); err != nil {
+		return "", err
+	}
+	return catalog.OutputExpression(dependency, key), nil
+}
+
+func (f *templateFuncs) relPath(path string) (string, error) {
+	rel, err := filepath.Rel(f.cfg.TargetFolder, filepath.Join(f.config.Terragrunt, path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (f *templateFuncs) manifest(keys ...string) (interface{}, error) {
+	switch len(keys) {
+	case 0:
+		return render(f.cfg.Metadata), nil
+	case 1:
+		value, ok := f.cfg.Metadata[keys[0]]
+		if !ok {
+			return nil, fmt.Errorf("manifest %s has no metadata %s", f.cfg.Manifest, keys[0])
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("manifest takes at most one key, got %d", len(keys))
+	}
+}
+
+func (f *templateFuncs) catalogType(name string) (map[string]interface{}, error) {
+	if f.catalog == nil {
+		return nil, fmt.Errorf("the catalog is not loaded")
+	}
+
+	serviceType, ok := f.catalog.Types[name]
+	if !ok {
+		return nil, skiff.NewServiceTypeDoesNotExistError(name)
+	}
+
+	outputs := make([]interface{}, 0, len(serviceType.Outputs))
+	for _, output := range serviceType.Outputs {
+		outputs = append(outputs, output)
+	}
+	return map[string]interface{}{
+		config.SourceKey:  serviceType.Source,
+		config.VersionKey: serviceType.Version,
+		config.GroupKey:   serviceType.Group,
+		"template":        serviceType.Template,
+		config.OutputsKey: outputs,
+	}, nil
+}
+
+func (f *templateFuncs) includeTemplate(name string) (string, error) {
+	if f.depth >= maxIncludeDepth {
+		return "", fmt.Errorf("include_template %s: templates include each other more than %d levels deep", name, maxIncludeDepth)
+	}
+
+	f.depth++
+	defer func() { f.depth-- }()
+
+	tmpl := f.templates.Lookup(name)
+	if tmpl == nil {
+		return "", fmt.Errorf("include_template %s: no such template in %s or the default library", name, f.config.Templates)
+	}
+
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&buff, nil); err != nil {
+		return "", fmt.Errorf("include_template %s: %w", name, err)
+	}
+	return buff.String(), nil
+}
+
+// hclValue writes value as an HCL expression, for example a map as an object.
+func hclValue(value interface{}) (string, error) {
+	tokens, err := valueTokens("value", render(value))
+	if err != nil {
+		return "", err
+	}
+	return string(hclwrite.Format(tokens.Bytes())), nil
+}
+
+// required returns value, or an error with message when value is missing or
+// an empty string.
+func required(message string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, errors.New(message)
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
@@ -0,0 +1,129 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/strategy"
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupFuncs(t *testing.T) *templateFuncs {
+	tempDir := t.TempDir()
+	context := types.TemplateContext{
+		config.ServiceKey: "eks",
+		config.InputsKey:  map[string]interface{}{"cluster_name": "platform"},
+		config.DependencyKey: []catalog.Dependency{
+			{config.ServiceKey: "vpc", config.ConfigPathKey: "../vpc"},
+		},
+		config.TerraformKey: map[string]interface{}{config.SourceKey: "github.com/org/eks?ref=1.0.0"},
+		config.BodyKey: map[string]interface{}{
+			config.InputsKey: map[string]interface{}{"cluster_name": "platform"},
+		},
+	}
+
+	return &templateFuncs{
+		cfg: strategy.Config{
+			Context:      &context,
+			TargetFolder: filepath.Join(tempDir, "terragrunt", "123", "us-east-1", "eks"),
+			Manifest:     "workload",
+			Service:      "eks",
+			Metadata:     types.Metadata{"account_id": "123"},
+		},
+		config: &config.Config{Path: config.Path{
+			Templates:  filepath.Join(tempDir, "templates"),
+			Terragrunt: filepath.Join(tempDir, "terragrunt"),
+		}},
+		catalog: &catalog.Catalog{Types: map[string]catalog.ServiceType{
+			"vpc": {Source: "github.com/org/vpc", Version: "1.0.0", Outputs: []string{"vpc_id"}},
+		}},
+	}
+}
+
+func executeString(f *templateFuncs, text string) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Funcs(f.funcMap()).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buff bytes.Buffer
+	err = tmpl.Execute(&buff, nil)
+	return buff.String(), err
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		template    string
+		expected    string
+		expectError string
+	}{
+		{name: "Var", template: `{{ var.service }}`, expected: "eks"},
+		{name: "HCL", template: `{{ hcl (dict "b" (list "x") "a" 1) }}`, expected: "{\n  a = 1\n  b = [\"x\"]\n}"},
+		{name: "Dependency", template: `{{ (dependency "vpc").config_path }}`, expected: "../vpc"},
+		{name: "Unknown dependency", template: `{{ dependency "rds" }}`, expectError: "eks does not depend on rds"},
+		{name: "Output", template: `{{ output "vpc" "vpc_id" }}`, expected: "dependency.vpc.outputs.vpc_id"},
+		{name: "Relpath", template: `{{ relpath "root.hcl" }}`, expected: "../../../root.hcl"},
+		{name: "Manifest key", template: `{{ manifest "account_id" }}`, expected: "123"},
+		{name: "Manifest metadata", template: `{{ (manifest).account_id }}`, expected: "123"},
+		{name: "Missing metadata", template: `{{ manifest "team" }}`, expectError: "manifest workload has no metadata team"},
+		{name: "Catalog", template: `{{ (catalog "vpc").source }} {{ index (catalog "vpc").outputs 0 }}`, expected: "github.com/org/vpc vpc_id"},
+		{name: "Unknown catalog type", template: `{{ catalog "rds" }}`, expectError: "service type rds does not exist"},
+		{name: "Required", template: `{{ required "cluster_name is required" var.inputs.cluster_name }}`, expected: "platform"},
+		{name: "Required empty", template: `{{ required "region is required" "" }}`, expectError: "region is required"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := executeString(setupFuncs(t), tc.template)
+			if tc.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, out)
+		})
+	}
+}
+
+func TestVarDoesNotChangeTheContext(t *testing.T) {
+	f := setupFuncs(t)
+
+	out, err := executeString(f, `{{ $vars := var }}{{ $_ := set $vars "service" "changed" }}{{ var.service }}
+{{ service_config }}`)
+	require.NoError(t, err)
+	assert.Contains(t, out, "eks\n")
+	assert.Contains(t, out, `cluster_name = "platform"`)
+
+	assert.Contains(t, *f.cfg.Context, config.TerraformKey)
+	assert.Contains(t, *f.cfg.Context, config.BodyKey)
+	assert.Equal(t, "eks", (*f.cfg.Context)[config.ServiceKey])
+}
+
+func TestIncludeTemplate(t *testing.T) {
+	f := setupFuncs(t)
+	require.NoError(t, os.MkdirAll(f.config.Templates, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(f.config.Templates, "locals.tmpl"), []byte(`locals {
+  account_id = "{{ manifest "account_id" }}"
+}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(f.config.Templates, "loop.tmpl"), []byte(`{{ include_template "loop.tmpl" }}`), 0644))
+
+	templates, err := loadTemplates(f.config.Templates, f.funcMap())
+	require.NoError(t, err)
+	require.NoError(t, f.bind(templates))
+
+	out, err := executeString(f, `{{ include_template "locals.tmpl" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "locals {\n  account_id = \"123\"\n}", out)
+
+	_, err = executeString(f, `{{ include_template "loop.tmpl" }}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than 10 levels deep")
+}
@@ -2,20 +2,27 @@ package template
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
-	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/types"
 	"github.com/zclconf/go-cty/cty"
 )
 
-func RenderToHCL(data interface{}) string {
+func RenderToHCL(data interface{}) (string, error) {
 	normalizedData := render(data)
 
 	root, ok := normalizedData.(map[string]interface{})
 	if !ok {
-		panic("rendered data is not a map[string]interface{}")
+		return "", fmt.Errorf("%s must be a map, got %T", config.BodyKey, data)
 	}
 
 	hclFile := hclwrite.NewEmptyFile()
@@ -25,24 +32,48 @@ func RenderToHCL(data interface{}) string {
 	if dependenciesRaw, ok := root["dependencies"]; ok {
 		dependenciesList, ok := dependenciesRaw.([]interface{})
 		if !ok {
-			panic("dependencies is not a []interface{}")
+			return "", skiff.NewInvalidValueError(config.DependencyKey, fmt.Sprintf("must be a list, got %T", dependenciesRaw))
 		}
 
-		WriteDependencyBlocks(body, dependenciesList)
+		if err := WriteDependencyBlocks(body, dependenciesList); err != nil {
+			return "", err
+		}
 
 		// Prevent duplicate rendering
 		delete(root, "dependencies")
 	}
 
-	writeMapToBody(body, root)
+	if providersRaw, ok := root[config.ProvidersKey]; ok {
+		providers, ok := providersRaw.(map[string]interface{})
+		if !ok {
+			return "", skiff.NewInvalidValueError(config.ProvidersKey, fmt.Sprintf("must be a map, got %T", providersRaw))
+		}
+		if len(providers) > 0 {
+			if err := WriteProviderGenerateBlock(body, providers); err != nil {
+				return "", err
+			}
+		}
+		delete(root, config.ProvidersKey)
+	}
 
-	return SanitizeExpressions(string(hclFile.Bytes()))
+	if err := writeMapToBody(body, root); err != nil {
+		return "", err
+	}
+
+	return string(hclwrite.Format(hclFile.Bytes())), nil
 }
 
 func render(data interface{}) interface{} {
 	value := reflect.ValueOf(data)
 
 	switch value.Kind() {
+	case reflect.String:
+		// expressions keep their type, other named strings become strings
+		if expr, ok := data.(types.Expression); ok {
+			return expr
+		}
+		return value.String()
+
 	case reflect.Map:
 		result := map[string]interface{}{}
 		for _, key := range value.MapKeys() {
@@ -95,110 +126,65 @@ func structToMap(in interface{}) map[string]interface{} {
 	return out
 }
 
-func ctyList(vals []interface{}) cty.Value {
-	var out []cty.Value
-	for _, v := range vals {
-		switch x := v.(type) {
-		case string:
-			out = append(out, cty.StringVal(x))
-		case int:
-			out = append(out, cty.NumberIntVal(int64(x)))
-		case int64:
-			out = append(out, cty.NumberIntVal(x))
-		case float64:
-			out = append(out, cty.NumberFloatVal(x))
-		case bool:
-			out = append(out, cty.BoolVal(x))
-		}
-	}
-
-	if len(out) == 0 {
-		// 🛡️ Provide an explicit type (defaulting to string for safety)
-		return cty.ListValEmpty(cty.String) // You can change this as needed
-	}
-	return cty.ListVal(out)
-}
-
-func mapToCtyObject(data map[string]interface{}) cty.Value {
-	obj := make(map[string]cty.Value)
-	for k, v := range data {
-		switch x := v.(type) {
-		case string:
-			obj[k] = cty.StringVal(x)
-		case int:
-			obj[k] = cty.NumberIntVal(int64(x))
-		case int64:
-			obj[k] = cty.NumberIntVal(x)
-		case float64:
-			obj[k] = cty.NumberFloatVal(x)
-		case bool:
-			obj[k] = cty.BoolVal(x)
-		case map[string]interface{}:
-			obj[k] = mapToCtyObject(x)
-		case []interface{}:
-			obj[k] = ctyList(x)
-		default:
-			fmt.Printf("⚠️ Skipping unsupported nested type %s\n", k)
-		}
-	}
-	return cty.ObjectVal(obj)
-}
-
-func ctyListOfObjects(data []map[string]interface{}) cty.Value {
-	var list []cty.Value
-	for _, item := range data {
-		list = append(list, mapToCtyObject(item))
-	}
-	return cty.ListVal(list)
-}
-
-func writeMapToBody(body *hclwrite.Body, data map[string]interface{}) {
-	for key, value := range data {
-		switch val := value.(type) {
-		case string:
-			body.SetAttributeValue(key, cty.StringVal(val))
-		case int:
-			body.SetAttributeValue(key, cty.NumberIntVal(int64(val)))
-		case int64:
-			body.SetAttributeValue(key, cty.NumberIntVal(val))
-		case float64:
-			body.SetAttributeValue(key, cty.NumberFloatVal(val))
-		case bool:
-			body.SetAttributeValue(key, cty.BoolVal(val))
-		case []interface{}:
-			if len(val) > 0 {
-				if _, ok := val[0].(map[string]interface{}); ok {
-					var maps []map[string]interface{}
-					for _, item := range val {
-						if m, ok := item.(map[string]interface{}); ok {
-							maps = append(maps, m)
-						}
-					}
-					body.SetAttributeValue(key, ctyListOfObjects(maps))
-					continue
-				}
-			}
-			body.SetAttributeValue(key, ctyList(val))
-		case map[string]interface{}:
-			body.SetAttributeValue(key, mapToCtyObject(val))
-		default:
-			fmt.Printf("⚠️ Unsupported type for key %s: %T\n", key, val)
+// dependencyAttributeOrder lists the attributes of a dependency block in the
+// order terragrunt documents them, other attributes follow sorted by name.
+var dependencyAttributeOrder = []string{
+	"config_path",
+	"enabled",
+	"skip_outputs",
+	"mock_outputs",
+	"mock_outputs_allowed_terraform_commands",
+	"mock_outputs_merge_strategy_with_state",
+}
+
+// orderedKeys returns the keys of data, those listed in order first and in
+// that order, then the others sorted, so the same data always renders the
+// same HCL.
+func orderedKeys(data map[string]interface{}, order ...string) []string {
+	keys := make([]string, 0, len(data))
+	for _, key := range order {
+		if _, ok := data[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		if !slices.Contains(order, key) {
+			keys = append(keys, key)
 		}
 	}
+	return keys
 }
 
-func WriteDependencyBlocks(body *hclwrite.Body, dependencies []interface{}) {
+func writeMapToBody(body *hclwrite.Body, data map[string]interface{}, order ...string) error {
+	for _, key := range orderedKeys(data, order...) {
+		tokens, err := valueTokens(key, data[key])
+		if err != nil {
+			return err
+		}
+		body.SetAttributeRaw(key, tokens)
+	}
+	return nil
+}
+
+// WriteDependencyBlocks appends a dependency block for every dependency,
+// ordered by service name.
+func WriteDependencyBlocks(body *hclwrite.Body, dependencies []interface{}) error {
+	dependencies = slices.Clone(dependencies)
+	slices.SortStableFunc(dependencies, func(a, b interface{}) int {
+		return strings.Compare(dependencyName(a), dependencyName(b))
+	})
+
 	for _, raw := range dependencies {
 		// Ensure it's a map
 		depMap, ok := raw.(map[string]interface{})
 		if !ok {
-			continue
+			return skiff.NewInvalidValueError(config.DependencyKey, fmt.Sprintf("dependency must be a map, got %T", raw))
 		}
 
 		// Extract the block label (service)
 		serviceName, ok := depMap["service"].(string)
-		if !ok {
-			continue
+		if !ok || serviceName == "" {
+			return skiff.NewInvalidValueError(config.DependencyKey, "dependency without a service")
 		}
 
 		// Copy all other keys except "service"
@@ -211,34 +197,192 @@ func WriteDependencyBlocks(body *hclwrite.Body, dependencies []interface{}) {
 
 		// Create Terragrunt-style block
 		block := body.AppendNewBlock("dependency", []string{serviceName})
-		writeMapToBody(block.Body(), blockData)
+		if err := writeMapToBody(block.Body(), blockData, dependencyAttributeOrder...); err != nil {
+			return fmt.Errorf("dependency %s: %w", serviceName, err)
+		}
 		body.AppendNewline()
 	}
+	return nil
 }
 
-func RenderTerraformAttrs(tf map[string]interface{}) string {
+func dependencyName(dependency interface{}) string {
+	depMap, _ := dependency.(map[string]interface{})
+	name, _ := depMap["service"].(string)
+	return name
+}
+
+func RenderTerraformAttrs(tf map[string]interface{}) (string, error) {
 	file := hclwrite.NewEmptyFile()
-	body := file.Body()
-	writeMapToBody(body, tf) // already exists
-	return strings.TrimSpace(string(file.Bytes()))
-}
-
-// SanitizeExpressions removes "__" prefix and unquotes dependency expressions
-// SanitizeExpressions handles expression unquoting and $$ escape fix
-func SanitizeExpressions(input string) string {
-	// Fix escaped dollar signs (e.g., $${foo} → ${foo})
-	input = strings.ReplaceAll(input, "$${", "${")
-
-	// Unquote __expression markers: "__dependency.foo.bar" → dependency.foo.bar
-	re := regexp.MustCompile(`= *"__([^"]+)"`)
-	input = re.ReplaceAllStringFunc(input, func(match string) string {
-		matches := re.FindStringSubmatch(match)
-		if len(matches) < 2 {
-			return match
-		}
-		expr := matches[1]
-		return "= " + expr
-	})
+	if err := writeMapToBody(file.Body(), tf); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(file.Bytes())), nil
+}
+
+// RenderRemoteState renders the remote_state block of a service from the
+// attributes built by the catalog: backend, generate and config.
+func RenderRemoteState(state map[string]interface{}) (string, error) {
+	file := hclwrite.NewEmptyFile()
+	block := file.Body().AppendNewBlock("remote_state", nil)
+
+	if err := writeMapToBody(block.Body(), state, "backend", "generate", "config"); err != nil {
+		return "", fmt.Errorf("remote_state: %w", err)
+	}
+	return strings.TrimSpace(string(file.Bytes())), nil
+}
+
+// providerFile is the file terragrunt generates the provider blocks into.
+const providerFile = "provider.tf"
+
+// defaultProviderBlocks lists, for the providers that have them, the settings
+// written as nested blocks rather than attributes. A provider overrides the
+// list with its blocks setting.
+var defaultProviderBlocks = map[string][]string{
+	"aws":     {"assume_role", "assume_role_with_web_identity", "default_tags", "ignore_tags", "endpoints"},
+	"azurerm": {"features"},
+}
+
+// WriteProviderGenerateBlock appends a terragrunt generate "provider" block
+// whose contents pin and configure every provider.
+func WriteProviderGenerateBlock(body *hclwrite.Body, providers map[string]interface{}) error {
+	contents, err := RenderProviders(providers)
+	if err != nil {
+		return err
+	}
+
+	block := body.AppendNewBlock("generate", []string{"provider"})
+	block.Body().SetAttributeValue("path", cty.StringVal(providerFile))
+	block.Body().SetAttributeValue("if_exists", cty.StringVal("overwrite_terragrunt"))
+	block.Body().SetAttributeRaw("contents", heredoc(contents))
+	body.AppendNewline()
+	return nil
+}
+
+// RenderProviders renders the required_providers pins and a provider block
+// for every provider and each of its aliases. Aliases inherit the provider
+// config and override it with their own settings.
+func RenderProviders(providers map[string]interface{}) (string, error) {
+	file := hclwrite.NewEmptyFile()
+	root := file.Body()
+	names := slices.Sorted(maps.Keys(providers))
+
+	required := root.AppendNewBlock("terraform", nil).Body().AppendNewBlock("required_providers", nil).Body()
+	for _, name := range names {
+		provider, _ := providers[name].(map[string]interface{})
+		pin := map[string]interface{}{config.SourceKey: provider[config.SourceKey]}
+		if version, _ := provider[config.VersionKey].(string); version != "" {
+			pin[config.VersionKey] = version
+		}
+		tokens, err := valueTokens(name, pin)
+		if err != nil {
+			return "", fmt.Errorf("provider %s: %w", name, err)
+		}
+		required.SetAttributeRaw(name, tokens)
+	}
+
+	for _, name := range names {
+		provider, _ := providers[name].(map[string]interface{})
+		settings, _ := provider["config"].(map[string]interface{})
+
+		blocks := defaultProviderBlocks[name]
+		if listed, ok := provider["blocks"].([]interface{}); ok {
+			blocks = nil
+			for _, block := range listed {
+				blocks = append(blocks, fmt.Sprint(block))
+			}
+		}
+
+		root.AppendNewline()
+		if err := writeProviderBlock(root, name, "", settings, blocks); err != nil {
+			return "", err
+		}
+
+		aliases, _ := provider["aliases"].(map[string]interface{})
+		for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+			aliasSettings := map[string]interface{}{}
+			maps.Copy(aliasSettings, settings)
+			if overrides, ok := aliases[alias].(map[string]interface{}); ok {
+				maps.Copy(aliasSettings, overrides)
+			}
+
+			root.AppendNewline()
+			if err := writeProviderBlock(root, name, alias, aliasSettings, blocks); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return string(file.Bytes()), nil
+}
+
+func writeProviderBlock(root *hclwrite.Body, name, alias string, settings map[string]interface{}, blocks []string) error {
+	body := root.AppendNewBlock("provider", []string{name}).Body()
+	if alias != "" {
+		body.SetAttributeValue("alias", cty.StringVal(alias))
+	}
+
+	path := "provider " + name
+	if alias != "" {
+		path += "." + alias
+	}
 
-	return input
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if nested, ok := settings[key].(map[string]interface{}); ok && slices.Contains(blocks, key) {
+			if err := writeMapToBody(body.AppendNewBlock(key, nil).Body(), nested); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, key, err)
+			}
+			continue
+		}
+
+		tokens, err := valueTokens(key, settings[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		body.SetAttributeRaw(key, tokens)
+	}
+	return nil
+}
+
+// heredoc returns the tokens of a heredoc string holding contents.
+func heredoc(contents string) hclwrite.Tokens {
+	if !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenOHeredoc, Bytes: []byte("<<EOF\n")},
+		{Type: hclsyntax.TokenStringLit, Bytes: []byte(contents)},
+		{Type: hclsyntax.TokenCHeredoc, Bytes: []byte("EOF")},
+	}
+}
+
+// formatHCL parses content, the rendered file at path, and returns it in the
+// canonical HCL format. Content that does not parse fails with an
+// InvalidHCLError locating the first error.
+func formatHCL(path string, content []byte) ([]byte, error) {
+	_, diags := hclparse.NewParser().ParseHCL(content, path)
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+
+		line, column := 0, 0
+		if diag.Subject != nil {
+			line, column = diag.Subject.Start.Line, diag.Subject.Start.Column
+		}
+		message := diag.Summary
+		if diag.Detail != "" {
+			message = fmt.Sprintf("%s; %s", diag.Summary, diag.Detail)
+		}
+		return nil, skiff.NewInvalidHCLError(path, line, column, sourceLine(content, line), message)
+	}
+	return hclwrite.Format(content), nil
+}
+
+// sourceLine returns line n of content, counting from 1.
+func sourceLine(content []byte, n int) string {
+	lines := strings.Split(string(content), "\n")
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[n-1], "\r")
 }
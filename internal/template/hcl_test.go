@@ -0,0 +1,146 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderToHCLExpressions(t *testing.T) {
+	body := map[string]interface{}{
+		"inputs": map[string]interface{}{
+			"name":   "__not_an_expression",
+			"prefix": "${literal}",
+			"vpc_id": types.Expression("dependency.vpc.outputs.vpc_id"),
+			"subnets": []interface{}{
+				types.Expression("dependency.vpc.outputs.private_subnets[0]"),
+				"10.0.1.0/24",
+			},
+			"tags": map[string]interface{}{
+				"team":  "platform",
+				"owner": types.Expression(`lower(get_env("OWNER", "ops"))`),
+			},
+		},
+	}
+
+	rendered, err := RenderToHCL(body)
+	require.NoError(t, err)
+	assert.Equal(t, `inputs = {
+  name    = "__not_an_expression"
+  prefix  = "$${literal}"
+  subnets = [dependency.vpc.outputs.private_subnets[0], "10.0.1.0/24"]
+  tags = {
+    owner = lower(get_env("OWNER", "ops"))
+    team  = "platform"
+  }
+  vpc_id = dependency.vpc.outputs.vpc_id
+}
+`, rendered)
+}
+
+func TestRenderToHCLProviders(t *testing.T) {
+	body := map[string]interface{}{
+		"inputs": map[string]interface{}{},
+		"providers": map[string]interface{}{
+			"aws": map[string]interface{}{
+				"source":  "hashicorp/aws",
+				"version": "~> 5.0",
+				"config": map[string]interface{}{
+					"region": "eu-west-1",
+					"assume_role": map[string]interface{}{
+						"role_arn": "arn:aws:iam::123456789012:role/deploy",
+					},
+					"default_tags": map[string]interface{}{
+						"tags": map[string]interface{}{"team": "platform"},
+					},
+				},
+				"aliases": map[string]interface{}{
+					"us_east_1": map[string]interface{}{"region": "us-east-1"},
+				},
+			},
+		},
+	}
+
+	rendered, err := RenderToHCL(body)
+	require.NoError(t, err)
+	assert.Equal(t, `generate "provider" {
+  path      = "provider.tf"
+  if_exists = "overwrite_terragrunt"
+  contents  = <<EOF
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  assume_role {
+    role_arn = "arn:aws:iam::123456789012:role/deploy"
+  }
+  default_tags {
+    tags = {
+      team = "platform"
+    }
+  }
+  region = "eu-west-1"
+}
+
+provider "aws" {
+  alias = "us_east_1"
+  assume_role {
+    role_arn = "arn:aws:iam::123456789012:role/deploy"
+  }
+  default_tags {
+    tags = {
+      team = "platform"
+    }
+  }
+  region = "us-east-1"
+}
+EOF
+}
+
+inputs = {}
+`, rendered)
+}
+
+func TestRenderProvidersBlocksOverride(t *testing.T) {
+	providers := map[string]interface{}{
+		"google": map[string]interface{}{
+			"source":  "hashicorp/google",
+			"version": "",
+			"config": map[string]interface{}{
+				"project":        "my-project",
+				"default_labels": map[string]interface{}{"team": "data"},
+				"batching":       map[string]interface{}{"send_after": "10s"},
+			},
+			"blocks": []interface{}{"batching"},
+		},
+	}
+
+	rendered, err := RenderProviders(providers)
+	require.NoError(t, err)
+	assert.Equal(t, `terraform {
+  required_providers {
+    google = {
+      source = "hashicorp/google"
+    }
+  }
+}
+
+provider "google" {
+  batching {
+    send_after = "10s"
+  }
+  default_labels = {
+    team = "data"
+  }
+  project = "my-project"
+}
+`, rendered)
+}
@@ -0,0 +1,119 @@
+package template
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// library holds the default templates and partials. A file of the templates
+// folder replaces the library file of the same name.
+//
+//go:embed all:library
+var library embed.FS
+
+const (
+	libraryRoot = "library"
+	// partialsFolder holds the templates shared by the others, each also
+	// named by its file name without extension, {{ template "locals" }}.
+	partialsFolder = "_partials"
+	templateExt    = ".tmpl"
+)
+
+// loadTemplates parses the library and every *.tmpl file below dir into one
+// template set. Templates are named by their slash separated path relative to
+// dir, for example terragrunt.default.tmpl or _partials/locals.tmpl. funcs
+// must hold every function the templates call, their implementation can be
+// replaced on a clone of the set.
+func loadTemplates(dir string, funcs template.FuncMap) (*template.Template, error) {
+	sources, err := templateSources(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	set := template.New("").Option("missingkey=error").Funcs(funcs)
+	for _, name := range slices.Sorted(maps.Keys(sources)) {
+		tmpl, err := set.New(name).Parse(sources[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+
+		if partial := partialName(name); partial != "" && tmpl.Tree != nil {
+			if _, err := set.AddParseTree(partial, tmpl.Tree); err != nil {
+				return nil, fmt.Errorf("failed to add partial %s: %w", name, err)
+			}
+		}
+	}
+	return set, nil
+}
+
+// templateSources reads the library and every *.tmpl file below dir, keyed by
+// template name. A missing dir leaves only the library.
+func templateSources(dir string) (map[string]string, error) {
+	sources := map[string]string{}
+
+	err := fs.WalkDir(library, libraryRoot, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(file) != templateExt {
+			return err
+		}
+		content, err := library.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		sources[strings.TrimPrefix(file, libraryRoot+"/")] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && file == dir {
+			return filepath.SkipDir
+		}
+		if err != nil || entry.IsDir() || filepath.Ext(file) != templateExt {
+			return err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		sources[filepath.ToSlash(name)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// partialName returns the short name of the partial called name, locals for
+// _partials/locals.tmpl, or "" when name is not a partial.
+func partialName(name string) string {
+	if dir, file := path.Split(name); dir == partialsFolder+"/" {
+		return strings.TrimSuffix(file, templateExt)
+	}
+	return ""
+}
+
+// templateName returns the name of the template at file in a set loaded from
+// dir, or "" when the file is outside dir.
+func templateName(dir, file string) string {
+	name, err := filepath.Rel(dir, file)
+	if err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(name)
+}
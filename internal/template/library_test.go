@@ -0,0 +1,78 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	f := setupFuncs(t)
+	files := map[string]string{
+		"_partials/tags.tmpl":      `tags = { service = "{{ var.service }}" }`,
+		"_partials/terraform.tmpl": `# terraform from the project`,
+		"_partials/blocks.tmpl":    `{{ define "marker" }}# {{ var.service }}{{ end }}`,
+		"services/eks.tmpl":        "{{ template \"terraform\" }}\n{{ template \"tags\" }}\n{{ template \"marker\" }}\n{{ template \"locals\" }}",
+		"notes.txt":                "not a template {{",
+	}
+	for name, content := range files {
+		path := filepath.Join(f.config.Templates, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	(*f.cfg.Context)["type"] = "eks"
+	(*f.cfg.Context)["region"] = "us-east-1"
+	(*f.cfg.Context)["scope"] = "regional"
+
+	templates, err := loadTemplates(f.config.Templates, f.funcMap())
+	require.NoError(t, err)
+	require.NoError(t, f.bind(templates))
+
+	out, err := f.execute(filepath.Join(f.config.Templates, "services", "eks.tmpl"))
+	require.NoError(t, err)
+	assert.Equal(t, `# terraform from the project
+tags = { service = "eks" }
+# eks
+locals {
+  service = "eks"
+  type    = "eks"
+  region  = "us-east-1"
+  scope   = "regional"
+}
+`, string(out))
+}
+
+func TestLoadTemplatesLibrary(t *testing.T) {
+	f := setupFuncs(t)
+
+	// without a templates folder the library still provides the default
+	templates, err := loadTemplates(f.config.Templates, f.funcMap())
+	require.NoError(t, err)
+	require.NoError(t, f.bind(templates))
+
+	out, err := f.execute(filepath.Join(f.config.Templates, "terragrunt.default.tmpl"))
+	require.NoError(t, err)
+	assert.Equal(t, `terraform {
+  source = "github.com/org/eks?ref=1.0.0"
+}
+
+inputs = {
+  cluster_name = "platform"
+}
+`, string(out))
+
+	_, err = f.execute(filepath.Join(f.config.Templates, "missing.tmpl"))
+	assert.Error(t, err)
+}
+
+func TestLoadTemplatesParseError(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{ if }}"), 0644))
+
+	_, err := loadTemplates(dir, (&templateFuncs{}).funcMap())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse template broken.tmpl")
+}
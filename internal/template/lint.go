@@ -0,0 +1,295 @@
+package template
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/manifest"
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+type (
+	// LintIssue is a problem found in a template without rendering it. Line
+	// is 0 when the problem has no position.
+	LintIssue struct {
+		Template string `json:"template" yaml:"template"`
+		Line     int    `json:"line,omitempty" yaml:"line,omitempty"`
+		Message  string `json:"message" yaml:"message"`
+	}
+
+	// LintReport lists the templates that were checked and their issues,
+	// sorted by template and line.
+	LintReport struct {
+		Templates []string    `json:"templates" yaml:"templates"`
+		Issues    []LintIssue `json:"issues" yaml:"issues"`
+	}
+
+	// linter holds what templates may refer to: the keys of var, the
+	// templates of the set and the catalog types.
+	linter struct {
+		serviceKeys map[string]bool
+		levelKeys   map[string]bool
+		levels      map[string]bool
+		names       map[string]bool
+		types       map[string]bool
+		issues      []LintIssue
+	}
+)
+
+// unavailableKeys are context keys var leaves out, with the function that
+// renders them instead.
+var unavailableKeys = map[string]string{
+	config.TerraformKey: config.TerraformAttributesKey,
+	config.BodyKey:      config.ServiceConfigKey,
+}
+
+// Lint parses the library and every template of the templates folder without
+// rendering them. It reports syntax errors, calls of unknown functions,
+// var.<key> references to keys the template context does not provide, and
+// template, include_template or catalog references to templates or service
+// types that do not exist.
+//
+// Service templates are checked against the keys BuildTemplateContext sets,
+// level templates of the configured strategy against the keys ResolveLevels
+// sets and partials against both. The metadata keys of every manifest and
+// the labels of every service are valid everywhere.
+func Lint(ctx context.Context) (*LintReport, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := templateSources(cfg.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := manifest.ReadAll(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{
+		serviceKeys: keySet(catalog.TemplateContextKeys),
+		levelKeys:   keySet(catalog.LevelContextKeys),
+		levels:      map[string]bool{},
+		names:       map[string]bool{},
+	}
+	for _, m := range manifests {
+		for key := range m.Metadata {
+			l.serviceKeys[key] = true
+			l.levelKeys[key] = true
+		}
+		for _, service := range m.Services {
+			for key := range service.Labels {
+				l.serviceKeys[key] = true
+			}
+		}
+	}
+	for _, level := range cfg.Strategy.Levels {
+		l.levels[filepath.ToSlash(level.Template)] = true
+	}
+
+	var serviceTypes catalog.Catalog
+	if err := serviceTypes.Read(filepath.Join(cfg.Manifests, config.CatalogFile)); err == nil {
+		l.types = keySet(slices.Collect(maps.Keys(serviceTypes.Types)))
+	}
+
+	funcs := (&templateFuncs{}).funcMap()
+	parsed := map[string]*template.Template{}
+	names := slices.Sorted(maps.Keys(sources))
+	for _, name := range names {
+		l.names[name] = true
+		if partial := partialName(name); partial != "" {
+			l.names[partial] = true
+		}
+
+		tmpl, err := template.New(name).Funcs(funcs).Parse(sources[name])
+		if err != nil {
+			l.parseError(name, err)
+			continue
+		}
+		parsed[name] = tmpl
+		for _, defined := range tmpl.Templates() {
+			l.names[defined.Name()] = true
+		}
+	}
+
+	for _, name := range names {
+		if tmpl, ok := parsed[name]; ok {
+			l.check(name, tmpl)
+		}
+	}
+
+	slices.SortStableFunc(l.issues, func(a, b LintIssue) int {
+		return cmp.Or(strings.Compare(a.Template, b.Template), cmp.Compare(a.Line, b.Line))
+	})
+	return &LintReport{Templates: names, Issues: l.issues}, nil
+}
+
+// WriteLint writes the lint report in format, the table listing one issue
+// per line.
+func WriteLint(w io.Writer, report *LintReport, format string) error {
+	return utils.WriteOutput(w, format, report, func(w io.Writer) error {
+		for _, issue := range report.Issues {
+			location := issue.Template
+			if issue.Line > 0 {
+				location = fmt.Sprintf("%s:%d", issue.Template, issue.Line)
+			}
+			fmt.Fprintf(w, "❌ %s: %s\n", location, issue.Message)
+		}
+		fmt.Fprintf(w, "%d templates, %d issues\n", len(report.Templates), len(report.Issues))
+		return nil
+	})
+}
+
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+// parseError records a parse error, taking the line from its
+// "template: name:line: message" form.
+func (l *linter) parseError(name string, err error) {
+	issue := LintIssue{Template: name, Message: err.Error()}
+
+	rest := strings.TrimPrefix(err.Error(), "template: "+name+":")
+	if line, message, ok := strings.Cut(rest, ": "); ok {
+		if n, convErr := strconv.Atoi(line); convErr == nil {
+			issue.Line, issue.Message = n, message
+		}
+	}
+	l.issues = append(l.issues, issue)
+}
+
+// check walks every tree parsed from the template called name, which are the
+// template itself and the ones it defines.
+func (l *linter) check(name string, tmpl *template.Template) {
+	keys := l.serviceKeys
+	switch {
+	case l.levels[name]:
+		keys = l.levelKeys
+	case partialName(name) != "":
+		keys = maps.Clone(l.serviceKeys)
+		maps.Copy(keys, l.levelKeys)
+	}
+
+	for _, defined := range tmpl.Templates() {
+		if defined.Tree == nil {
+			continue
+		}
+		tree := defined.Tree
+		walk(tree.Root, func(node parse.Node) {
+			if message := l.checkNode(node, keys); message != "" {
+				l.issues = append(l.issues, LintIssue{Template: name, Line: nodeLine(tree, node), Message: message})
+			}
+		})
+	}
+}
+
+// checkNode returns the problem with node, or "" when there is none.
+func (l *linter) checkNode(node parse.Node, keys map[string]bool) string {
+	switch n := node.(type) {
+	case *parse.ChainNode:
+		ident, ok := n.Node.(*parse.IdentifierNode)
+		if !ok || ident.Ident != config.VarKey || len(n.Field) == 0 {
+			return ""
+		}
+		key := n.Field[0]
+		if function, ok := unavailableKeys[key]; ok {
+			return fmt.Sprintf("var.%s is not available in templates, use %s", key, function)
+		}
+		if !keys[key] {
+			return fmt.Sprintf("var.%s is not a key of the template context", key)
+		}
+
+	case *parse.TemplateNode:
+		if !l.names[n.Name] {
+			return fmt.Sprintf("template %q is not defined", n.Name)
+		}
+
+	case *parse.CommandNode:
+		if len(n.Args) != 2 {
+			return ""
+		}
+		ident, ok := n.Args[0].(*parse.IdentifierNode)
+		arg, isString := n.Args[1].(*parse.StringNode)
+		if !ok || !isString {
+			return ""
+		}
+		switch {
+		case ident.Ident == config.IncludeTemplateKey && !l.names[arg.Text]:
+			return fmt.Sprintf("include_template %q is not a template of the set", arg.Text)
+		case ident.Ident == config.CatalogKey && l.types != nil && !l.types[arg.Text]:
+			return fmt.Sprintf("catalog %q is not a service type of the catalog", arg.Text)
+		}
+	}
+	return ""
+}
+
+// walk calls visit for node and every node below it.
+func walk(node parse.Node, visit func(parse.Node)) {
+	visit(node)
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			walk(child, visit)
+		}
+	case *parse.ActionNode:
+		walk(n.Pipe, visit)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, visit)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, visit)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, visit)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			walk(n.Pipe, visit)
+		}
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			walk(cmd, visit)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walk(arg, visit)
+		}
+	case *parse.ChainNode:
+		walk(n.Node, visit)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, visit func(parse.Node)) {
+	walk(n.Pipe, visit)
+	walk(n.List, visit)
+	if n.ElseList != nil {
+		walk(n.ElseList, visit)
+	}
+}
+
+// nodeLine returns the line of node in the source of tree.
+func nodeLine(tree *parse.Tree, node parse.Node) int {
+	location, _ := tree.ErrorContext(node)
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
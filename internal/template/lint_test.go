@@ -0,0 +1,47 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# {{ var.account_id }} {{ var.service }}\n",
+		"templates/eks.tmpl": `{{ template "locals" }}
+{{ var.account_id }} {{ var.inputs.cidr }}
+{{ if var.region }}{{ var.missing }}{{ end }}
+{{ var.body }}
+{{ include_template "nope.tmpl" }}
+{{ catalog "rds" }}
+{{ template "undefined" }}`,
+		"templates/broken.tmpl":  "ok\n{{ if }}",
+		"templates/unknown.tmpl": "{{ not_a_function }}",
+	})
+	ctx := context.WithValue(context.Background(), "config", cfg)
+
+	report, err := Lint(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, report.Templates, "eks.tmpl")
+	assert.Contains(t, report.Templates, "_partials/locals.tmpl")
+
+	assert.Equal(t, []LintIssue{
+		{Template: "account.hcl.tmpl", Line: 1, Message: "var.service is not a key of the template context"},
+		{Template: "broken.tmpl", Line: 2, Message: "missing value for if"},
+		{Template: "eks.tmpl", Line: 3, Message: "var.missing is not a key of the template context"},
+		{Template: "eks.tmpl", Line: 4, Message: "var.body is not available in templates, use service_config"},
+		{Template: "eks.tmpl", Line: 5, Message: `include_template "nope.tmpl" is not a template of the set`},
+		{Template: "eks.tmpl", Line: 6, Message: `catalog "rds" is not a service type of the catalog`},
+		{Template: "eks.tmpl", Line: 7, Message: `template "undefined" is not defined`},
+		{Template: "unknown.tmpl", Line: 1, Message: `function "not_a_function" not defined`},
+	}, report.Issues)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteLint(&out, report, "table"))
+	assert.Contains(t, out.String(), "❌ eks.tmpl:3: var.missing is not a key of the template context\n")
+	assert.Contains(t, out.String(), "8 issues\n")
+}
@@ -0,0 +1,70 @@
+package template
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+const (
+	FileWritten   = "written"
+	FileUnchanged = "unchanged"
+	// FileSkipped is a file a dry run would have written.
+	FileSkipped = "skipped"
+)
+
+type (
+	// GeneratedFile is a file rendered by generate. Level is set for the
+	// files of strategy levels, Manifest and Service then name the first
+	// service below the level. Content is only set for the files a dry run
+	// skipped.
+	GeneratedFile struct {
+		Path     string `json:"path" yaml:"path"`
+		Manifest string `json:"manifest" yaml:"manifest"`
+		Service  string `json:"service" yaml:"service"`
+		Level    string `json:"level,omitempty" yaml:"level,omitempty"`
+		Status   string `json:"status" yaml:"status"`
+		Content  string `json:"content,omitempty" yaml:"content,omitempty"`
+	}
+
+	// GenerateReport lists the files of a generate run in rendering order.
+	GenerateReport struct {
+		DryRun bool            `json:"dry_run" yaml:"dry_run"`
+		Files  []GeneratedFile `json:"files" yaml:"files"`
+	}
+)
+
+// Count returns the number of files with status.
+func (r *GenerateReport) Count(status string) int {
+	count := 0
+	for _, file := range r.Files {
+		if file.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteGenerateReport writes the report in format. The text output lists one
+// file per line, with the content of the files a dry run skipped.
+func WriteGenerateReport(w io.Writer, report *GenerateReport, format string) error {
+	return utils.WriteOutput(w, format, report, func(w io.Writer) error {
+		for _, file := range report.Files {
+			switch file.Status {
+			case FileWritten:
+				fmt.Fprintf(w, "✅ Rendered: %s\n", file.Path)
+			case FileUnchanged:
+				fmt.Fprintf(w, "➖ Unchanged: %s\n", file.Path)
+			case FileSkipped:
+				fmt.Fprintf(w, "🧪 [Dry Run] Would render: %s\n", file.Path)
+				fmt.Fprintln(w, file.Content)
+			}
+		}
+		fmt.Fprintf(
+			w, "%d written, %d unchanged, %d skipped\n",
+			report.Count(FileWritten), report.Count(FileUnchanged), report.Count(FileSkipped),
+		)
+		return nil
+	})
+}
@@ -5,17 +5,14 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 	"text/template"
 
-	"github.com/Masterminds/sprig"
 	"github.com/nyambati/skiff/internal/catalog"
 	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
 	"github.com/nyambati/skiff/internal/manifest"
 	"github.com/nyambati/skiff/internal/strategy"
-	"github.com/nyambati/skiff/internal/types"
-	"github.com/sirupsen/logrus"
+	"github.com/nyambati/skiff/internal/utils"
 )
 
 // getRenderConfig retrieves the render configuration based on the provided strategy name,
@@ -25,22 +22,39 @@ import (
 // during processing.
 
 func GetRenderConfig(ctx context.Context, manifestID, labels string) (*strategy.RenderConfig, error) {
+	configs, _, err := getRenderConfig(ctx, manifestID, labels)
+	return configs, err
+}
+
+func getRenderConfig(ctx context.Context, manifestID, labels string) (*strategy.RenderConfig, *catalog.Catalog, error) {
 	var catalog catalog.Catalog
 	cfg, err := config.FromContext(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	serviceTypesPath := fmt.Sprintf("%s/%s", cfg.Manifests, config.CatalogFile)
 	if err := catalog.Read(serviceTypesPath); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	manifests, err := loadManifests(ctx, manifestID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// a single manifest can still collide with the ones it is not rendered with
+	if manifestID != "" {
+		others, err := manifest.ReadOthers(ctx, manifestID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := strategy.CheckCollisions(append(manifests, others...)); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return strategy.Execute(ctx, manifests, &catalog, labels), nil
+	configs, err := strategy.Execute(ctx, manifests, &catalog, labels)
+	return configs, &catalog, err
 }
 
 // loadManifests reads the account manifests from the manifests folder based on the provided
@@ -48,137 +62,143 @@ func GetRenderConfig(ctx context.Context, manifestID, labels string) (*strategy.
 // folder. It returns a slice of pointers to Manifest and an error if any issues occur during
 // processing.
 func loadManifests(ctx context.Context, manifestID string) ([]*manifest.Manifest, error) {
-	var manifests []*manifest.Manifest
-
-	cfg, err := config.FromContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	accounts, err := getManifestIdetifiers(manifestID, cfg.Manifests)
+	manifests, err := manifest.ReadAll(ctx, manifestID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, accountID := range accounts {
-		accountID = strings.TrimSuffix(accountID, filepath.Ext(accountID))
-
-		m, err := manifest.Read(ctx, accountID)
-		if err != nil {
-			return nil, err
-		}
+	for _, m := range manifests {
 		if err := m.Resolve(ctx); err != nil {
 			return nil, err
 		}
-		manifests = append(manifests, m)
 	}
 	return manifests, nil
 }
 
-// getAccountIDs reads the account manifest IDs from the manifests folder based on the
-// provided account ID. If an empty string is provided, it reads all account manifest IDs
-// in the folder. It returns a slice of strings containing the account IDs and an error
-// if any issues occur during processing.
-func getManifestIdetifiers(manifestName, manifestPath string) ([]string, error) {
-	if manifestName != "" {
-		return []string{manifestName}, nil
-	}
-
-	manifestDir, err := os.ReadDir(manifestPath)
+// Render generates Terragrunt configuration files based on the provided strategy,
+// account ID, and labels. It retrieves the rendering configuration and parses the
+// specified templates. If dryRun is true, nothing is written and the report holds
+// the content of every file that would change. Otherwise, it creates the necessary
+// directories and writes the rendered files to the specified target folders. When a
+// backend is configured the remote_state block is appended to service files whose
+// template does not render it with the remote_state function. Level files shared by
+// several services are written once and must render identically for all of them.
+//
+// Every file is rendered before any is written, and each is written through a
+// temporary file renamed into place, so a failure never leaves a partial set
+// of files or a truncated file behind. Files whose content did not change are
+// left untouched. Rendering failures are returned as RenderError values naming
+// the manifest, service, template and output file.
+
+func Render(ctx context.Context, manifestID, labels string, dryRun bool) (*GenerateReport, error) {
+	skiffConfig, err := config.FromContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read manifests directory: %w", err)
-	}
-
-	var manifestIDs []string
-	for _, entry := range manifestDir {
-		if isValidIdentifier(entry) {
-			manifestIDs = append(manifestIDs, entry.Name())
-		}
+		return nil, err
 	}
 
-	if len(manifestIDs) == 0 {
-		return nil, fmt.Errorf("no account manifests found in %s", manifestPath)
+	configs, catalog, err := getRenderConfig(ctx, manifestID, labels)
+	if err != nil {
+		return nil, err
 	}
 
-	return manifestIDs, nil
-}
-
-func isValidIdentifier(entry os.DirEntry) bool {
-	return !entry.IsDir() &&
-		entry.Name() != config.CatalogFile &&
-		strings.HasSuffix(entry.Name(), ".yaml")
-}
-
-// Render generates Terragrunt configuration files based on the provided strategy,
-// account ID, and labels. It retrieves the rendering configuration and parses the
-// specified templates. If dryRun is true, it only prints the rendered output without
-// writing to files. Otherwise, it creates the necessary directories and writes the
-// rendered files to the specified target folders. Returns an error if any issues occur
-// during the rendering process.
-
-func Render(ctx context.Context, manifestID, labels string, dryRun bool) error {
-	configs, err := GetRenderConfig(ctx, manifestID, labels)
+	templates, err := loadTemplates(skiffConfig.Templates, (&templateFuncs{}).funcMap())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var outputs []output
+	rendered := map[string][]byte{}
 	for _, cfg := range *configs {
-		funcMaps := sprig.TxtFuncMap()
-		funcMaps[config.TerraformAttributesKey] = func() string {
-			terraform, ok := (*cfg.Context)[config.TerraformKey].(map[string]interface{})
-			if !ok {
-				logrus.Fatal(fmt.Errorf("terraform is not a map[string]interface{}"))
-			}
-			return RenderTerraformAttrs(terraform)
+		outputPath := cfg.OutputPath()
+
+		content, err := renderConfig(cfg, skiffConfig, catalog, templates)
+		if err != nil {
+			return nil, skiff.NewRenderError(cfg.Manifest, cfg.Service, cfg.Level, cfg.Template, outputPath, err)
 		}
 
-		funcMaps[config.ServiceConfigKey] = func() string {
-			body, ok := (*cfg.Context)[config.BodyKey].(map[string]interface{})
-			if !ok {
-				logrus.Fatal(fmt.Errorf("terraform is not a map[string]interface{}"))
+		// level files are shared by the services below them and written once
+		if previous, exists := rendered[outputPath]; exists {
+			if !bytes.Equal(previous, content) {
+				return nil, fmt.Errorf(
+					"level %s renders %s differently for the services sharing the folder, level templates may only use variables that are the same for the whole folder",
+					cfg.Level, outputPath,
+				)
 			}
-			return RenderToHCL(body)
-
+			continue
 		}
+		rendered[outputPath] = content
+		outputs = append(outputs, output{
+			folder:  cfg.TargetFolder,
+			content: content,
+			file: GeneratedFile{
+				Path:     outputPath,
+				Manifest: cfg.Manifest,
+				Service:  cfg.Service,
+				Level:    cfg.Level,
+			},
+		})
+	}
 
-		funcMaps[config.VarKey] = func() types.TemplateContext {
-			delete(*cfg.Context, config.TerraformKey)
-			delete(*cfg.Context, config.BodyKey)
-			return *cfg.Context
-		}
+	report := &GenerateReport{DryRun: dryRun, Files: make([]GeneratedFile, 0, len(outputs))}
+	for _, out := range outputs {
+		file := out.file
 
-		tmpl, err := template.New("").Option("missingkey=error").Funcs(funcMaps).ParseFiles(cfg.Template)
-		if err != nil {
-			return fmt.Errorf("failed to parse template: %w", err)
+		if existing, err := os.ReadFile(file.Path); err == nil && bytes.Equal(existing, out.content) {
+			file.Status = FileUnchanged
+			report.Files = append(report.Files, file)
+			continue
 		}
 
-		outputPath := filepath.Join(cfg.TargetFolder, "terragrunt.hcl")
-
 		if dryRun {
-			var buff bytes.Buffer
-			if err := tmpl.ExecuteTemplate(&buff, filepath.Base(cfg.Template), nil); err != nil {
-				return fmt.Errorf("failed to render template to %s: %w", outputPath, err)
-			}
-			logrus.
-				Infof("🧪 [Dry Run] Would render: %s\n", outputPath)
-			fmt.Println(buff.String())
+			file.Status = FileSkipped
+			file.Content = string(out.content)
+			report.Files = append(report.Files, file)
 			continue
 		}
 
 		// Ensure target folder exists
-		if err := os.MkdirAll(cfg.TargetFolder, 0755); err != nil {
-			return fmt.Errorf("failed to create folder %s: %w", cfg.TargetFolder, err)
+		if err := os.MkdirAll(out.folder, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create folder %s: %w", out.folder, err)
 		}
 
-		file, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", outputPath, err)
+		if err := utils.WriteFileAtomic(file.Path, out.content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write file %s: %w", file.Path, err)
 		}
-		defer file.Close()
-		if err := tmpl.ExecuteTemplate(file, filepath.Base(cfg.Template), nil); err != nil {
-			return fmt.Errorf("failed to render template to %s: %w", outputPath, err)
+		file.Status = FileWritten
+		report.Files = append(report.Files, file)
+	}
+	return report, nil
+}
+
+// output is a rendered file waiting to be written.
+type output struct {
+	folder  string
+	content []byte
+	file    GeneratedFile
+}
+
+// renderConfig renders the template of cfg with the template functions,
+// appends the remote_state block when the template does not place it and
+// returns the result validated and formatted as HCL.
+func renderConfig(cfg strategy.Config, skiffConfig *config.Config, catalog *catalog.Catalog, templates *template.Template) ([]byte, error) {
+	funcs := &templateFuncs{cfg: cfg, config: skiffConfig, catalog: catalog}
+	if err := funcs.bind(templates); err != nil {
+		return nil, err
+	}
+
+	content, err := funcs.execute(cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	// templates that do not place the remote_state block get it appended
+	remoteState, hasRemoteState := (*cfg.Context)[config.RemoteStateKey].(map[string]interface{})
+	if hasRemoteState && !funcs.remoteStateRendered {
+		block, err := RenderRemoteState(remoteState)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Printf("✅ Rendered: %s\n", outputPath)
+		content = fmt.Appendf(content, "\n%s\n", block)
 	}
-	return nil
+	return formatHCL(cfg.OutputPath(), content)
 }
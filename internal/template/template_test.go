@@ -1,61 +1,388 @@
 package template
 
 import (
+	"bytes"
+	"context"
+	"flag"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-var skiffConfig *config.Config
+func setupProject(t *testing.T, files map[string]string) *config.Config {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Path: config.Path{
+			Manifests:  filepath.Join(tempDir, "manifests"),
+			Templates:  filepath.Join(tempDir, "templates"),
+			Terragrunt: filepath.Join(tempDir, "terragrunt"),
+		},
+		Strategy: config.Strategy{
+			Template: "{{ var.account_id }}/{{ var.region }}/{{ var.service }}",
+			Levels: []config.Level{
+				{Name: "root", Template: "root.hcl.tmpl"},
+				{Name: "account", Path: "{{ var.account_id }}", Template: "account.hcl.tmpl"},
+			},
+		},
+	}
 
-func setupTestManifestsDir(t *testing.T) string {
-	// Create a temporary directory for test manifests
-	tempDir, err := os.MkdirTemp("", "skiff")
-	require.NoError(t, err)
+	files["manifests/catalog.yaml"] = "types:\n  vpc:\n    source: github.com/org/vpc\n    version: 1.0.0\n"
+	files["manifests/workload.yaml"] = `metadata:
+  account_id: "123"
+services:
+  vpc:
+    type: vpc
+    region: us-east-1
+  vpc-eu:
+    type: vpc
+    region: eu-west-1
+`
+	files["templates/terragrunt.default.tmpl"] = `include "root" {
+  path = "{{ var.levels.root }}"
+}
+`
+	files["templates/root.hcl.tmpl"] = "# root\n"
+
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return cfg
+}
+
+func TestRenderLevels(t *testing.T) {
+	t.Run("Shared files are written once per folder", func(t *testing.T) {
+		cfg := setupProject(t, map[string]string{
+			"templates/account.hcl.tmpl": "locals {\n  account_id = \"{{ var.account_id }}\"\n}\n",
+		})
+		ctx := context.WithValue(context.Background(), "config", cfg)
+
+		_, err := Render(ctx, "", "", false)
+		require.NoError(t, err)
+
+		root, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "root.hcl"))
+		require.NoError(t, err)
+		assert.Equal(t, "# root\n", string(root))
+
+		account, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "123", "account.hcl"))
+		require.NoError(t, err)
+		assert.Equal(t, "locals {\n  account_id = \"123\"\n}\n", string(account))
+
+		service, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "123", "us-east-1", "vpc", config.TerragruntFile))
+		require.NoError(t, err)
+		assert.Equal(t, "include \"root\" {\n  path = \"../../../root.hcl\"\n}\n", string(service))
+	})
+
+	t.Run("Folder specific variables are rejected", func(t *testing.T) {
+		cfg := setupProject(t, map[string]string{
+			"templates/account.hcl.tmpl": "# {{ var.region }}\n",
+		})
+		ctx := context.WithValue(context.Background(), "config", cfg)
+
+		_, err := Render(ctx, "", "", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "level account renders "+filepath.Join(cfg.Terragrunt, "123", "account.hcl")+" differently")
+	})
+}
 
-	// Ensure config is initialized
-	if skiffConfig == nil {
-		skiffConfig = &config.Config{}
+func TestRenderRemoteState(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+		"manifests/shared.yaml": `metadata:
+  account_id: "456"
+backend:
+  config:
+    bucket: shared-state
+services:
+  dns:
+    type: vpc
+    region: us-east-1
+`,
+	})
+	cfg.Backend = &config.Backend{
+		Type:     config.BackendS3,
+		Generate: "backend.tf",
+		Config:   map[string]any{"bucket": "state", "region": "us-east-1"},
 	}
+	ctx := context.WithValue(context.Background(), "config", cfg)
+
+	_, err := Render(ctx, "", "", false)
+	require.NoError(t, err)
+
+	service, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "123", "us-east-1", "vpc", config.TerragruntFile))
+	require.NoError(t, err)
+	assert.Equal(t, `include "root" {
+  path = "../../../root.hcl"
+}
 
-	// Backup and modify the config
-	originalManifestsDir := skiffConfig.Manifests
-	t.Cleanup(func() {
-		skiffConfig.Manifests = originalManifestsDir
-		os.RemoveAll(tempDir)
+remote_state {
+  backend = "s3"
+  generate = {
+    if_exists = "overwrite_terragrunt"
+    path      = "backend.tf"
+  }
+  config = {
+    bucket = "state"
+    key    = "123/us-east-1/vpc/terraform.tfstate"
+    region = "us-east-1"
+  }
+}
+`, string(service))
+
+	// the manifest backend overrides the bucket of the .skiff backend
+	shared, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "456", "us-east-1", "dns", config.TerragruntFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(shared), `bucket = "shared-state"`)
+	assert.Contains(t, string(shared), `key    = "456/us-east-1/dns/terraform.tfstate"`)
+
+	// root and account level files never get a remote_state block
+	root, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "root.hcl"))
+	require.NoError(t, err)
+	assert.Equal(t, "# root\n", string(root))
+}
+
+func TestRenderCollisionWithOtherManifests(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+		"manifests/shared.yaml": `metadata:
+  account_id: "123"
+services:
+  vpc:
+    type: vpc
+    region: us-east-1
+`,
 	})
-	skiffConfig.Manifests = tempDir
+	ctx := context.WithValue(context.Background(), "config", cfg)
+
+	// workload is not rendered, but generating shared would overwrite its vpc
+	_, err := Render(ctx, "shared", "", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resolve to the same target path 123/us-east-1/vpc")
+	assert.NoDirExists(t, cfg.Terragrunt)
+}
+
+func TestRenderIgnoresInvalidOtherManifests(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+		"manifests/broken.yaml": `services:
+  vpc:
+    type: unknown
+`,
+		"manifests/half-edited.yaml": "services:\n  vpc: [\n",
+	})
+	ctx := context.WithValue(context.Background(), "config", cfg)
+
+	report, err := Render(ctx, "workload", "", false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, report.Files)
+	assert.FileExists(t, filepath.Join(cfg.Terragrunt, "123", "us-east-1", "vpc", config.TerragruntFile))
+
+	// the selected manifest still fails on its own errors
+	_, err = Render(ctx, "broken", "", false)
+	assert.Error(t, err)
+}
 
-	return tempDir
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestRenderGolden(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+		"manifests/platform.yaml": `metadata:
+  account_id: "789"
+services:
+  vpc:
+    type: vpc
+    region: us-east-1
+    inputs:
+      cidr: 10.0.0.0/16
+      azs: [us-east-1a, us-east-1b]
+      tags:
+        team: platform
+        cost_center: "42"
+  eks:
+    type: eks
+    region: us-east-1
+    inputs:
+      cluster_name: platform
+      node_groups:
+        general:
+          min_size: 1
+          max_size: 3
+          instance_types: [m6i.large]
+      name: !expr format("%s-eks", local.env)
+    dependencies:
+      - service: vpc
+        mock_outputs:
+          vpc_id: vpc-123
+        skip_outputs: false
+  dns:
+    type: vpc
+    region: us-east-1
+    dependencies:
+      - service: vpc
+      - service: eks
+`,
+	})
+	overwrite := map[string]string{
+		"manifests/catalog.yaml": `types:
+  vpc:
+    source: github.com/org/vpc
+    version: 1.0.0
+    outputs: [vpc_id, private_subnets]
+  eks:
+    source: github.com/org/eks
+    version: 2.0.0
+    outputs: [cluster_endpoint]
+`,
+		"templates/terragrunt.default.tmpl": `include "root" {
+  path = "{{ var.levels.root }}"
 }
 
-func TestGetManifestIDs(t *testing.T) {
-	tempDir := setupTestManifestsDir(t)
+terraform {
+{{ terraform_attributes }}
+}
 
-	// Create some test manifest files
-	testFiles := []string{
-		"account1.yaml",
-		"account2.yaml",
-		"catalog.yaml", // should be ignored
+{{ service_config }}`,
+	}
+	for name, content := range overwrite {
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(cfg.Manifests), name), []byte(content), 0644))
 	}
+	ctx := context.WithValue(context.Background(), "config", cfg)
 
-	for _, filename := range testFiles {
-		fullPath := filepath.Join(tempDir, filename)
-		err := os.WriteFile(fullPath, []byte("test content"), 0644)
+	// map iteration order changes between runs, rendering repeatedly must
+	// give the same bytes
+	for run := 0; run < 10; run++ {
+		_, err := Render(ctx, "platform", "", false)
 		require.NoError(t, err)
+
+		for _, service := range []string{"dns", "eks", "vpc"} {
+			rendered, err := os.ReadFile(filepath.Join(cfg.Terragrunt, "789", "us-east-1", service, config.TerragruntFile))
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", service+".golden.hcl")
+			if *update && run == 0 {
+				require.NoError(t, os.MkdirAll("testdata", 0755))
+				require.NoError(t, os.WriteFile(golden, rendered, 0644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(rendered), "run %d of %s", run, service)
+		}
 	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+		"templates/broken.tmpl":      "{{ service_config }}\n{{ var.missing }}\n",
+	})
+	// vpc-eu uses a broken template, nothing may be written for any service
+	catalog := "types:\n  vpc:\n    source: github.com/org/vpc\n    version: 1.0.0\n  broken:\n    source: github.com/org/vpc\n    version: 1.0.0\n    template: broken.tmpl\n"
+	manifest := "metadata:\n  account_id: \"123\"\nservices:\n  vpc:\n    type: vpc\n    region: us-east-1\n  vpc-eu:\n    type: broken\n    region: eu-west-1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Manifests, "catalog.yaml"), []byte(catalog), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Manifests, "workload.yaml"), []byte(manifest), 0644))
+	ctx := context.WithValue(context.Background(), "config", cfg)
+
+	_, err := Render(ctx, "", "", false)
+	require.Error(t, err)
+
+	var renderErr *skiff.RenderError
+	require.ErrorAs(t, err, &renderErr)
+	assert.Equal(t, "workload", renderErr.Manifest)
+	assert.Equal(t, "vpc-eu", renderErr.Service)
+	assert.Equal(t, filepath.Join(cfg.Templates, "broken.tmpl"), renderErr.Template)
+	assert.Equal(t, filepath.Join(cfg.Terragrunt, "123", "eu-west-1", "vpc-eu", config.TerragruntFile), renderErr.Output)
+	assert.Contains(t, err.Error(), "missing")
+
+	_, statErr := os.Stat(cfg.Terragrunt)
+	assert.True(t, os.IsNotExist(statErr), "no file is written when a service fails to render")
+}
+
+func TestRenderToHCLErrors(t *testing.T) {
+	_, err := RenderToHCL(map[string]interface{}{"dependencies": "vpc"})
+	assert.Error(t, err)
+
+	_, err = RenderToHCL(map[string]interface{}{"inputs": map[string]interface{}{"bad": make(chan int)}})
+	var invalid *skiff.InvalidValueError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "inputs.bad", invalid.Key)
+}
+
+func TestRenderInvalidHCL(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+	})
+	template := "terraform {\n      source = \"{{ var.service }}\"\n}\n\ninputs = {\n  name = \"{{ var.service }}\" x\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Templates, "terragrunt.default.tmpl"), []byte(template), 0644))
+	ctx := context.WithValue(context.Background(), "config", cfg)
+
+	_, err := Render(ctx, "", "", false)
+	require.Error(t, err)
+
+	var renderErr *skiff.RenderError
+	require.ErrorAs(t, err, &renderErr)
+	assert.Equal(t, filepath.Join(cfg.Templates, "terragrunt.default.tmpl"), renderErr.Template)
 
-	// Test with empty accountID (should return all non-service-types files)
-	accountIDs, err := getManifestIdetifiers("", tempDir)
+	var invalid *skiff.InvalidHCLError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, renderErr.Output, invalid.File)
+	assert.Equal(t, 6, invalid.Line)
+	assert.Equal(t, `  name = "vpc" x`, invalid.Snippet)
+	assert.Contains(t, err.Error(), `6 |   name = "vpc" x`)
+
+	_, statErr := os.Stat(cfg.Terragrunt)
+	assert.True(t, os.IsNotExist(statErr), "no file is written when an output is not valid HCL")
+}
+
+func TestFormatHCL(t *testing.T) {
+	formatted, err := formatHCL("terragrunt.hcl", []byte("terraform {\n      source = \"x\"\n}\ninputs = {\n a = 1\n    bb = 2\n}\n"))
 	require.NoError(t, err)
-	assert.ElementsMatch(t, []string{"account1.yaml", "account2.yaml"}, accountIDs)
+	assert.Equal(t, "terraform {\n  source = \"x\"\n}\ninputs = {\n  a  = 1\n  bb = 2\n}\n", string(formatted))
+}
+
+func TestRenderReport(t *testing.T) {
+	cfg := setupProject(t, map[string]string{
+		"templates/account.hcl.tmpl": "# account\n",
+	})
+	ctx := context.WithValue(context.Background(), "config", cfg)
+	vpc := filepath.Join(cfg.Terragrunt, "123", "us-east-1", "vpc", config.TerragruntFile)
+
+	report, err := Render(ctx, "", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Count(FileWritten))
+	assert.Contains(t, report.Files, GeneratedFile{Path: vpc, Manifest: "workload", Service: "vpc", Status: FileWritten})
+	assert.Contains(t, report.Files, GeneratedFile{
+		Path:     filepath.Join(cfg.Terragrunt, "123", "account.hcl"),
+		Manifest: "workload",
+		Service:  "vpc",
+		Level:    "account",
+		Status:   FileWritten,
+	})
+
+	report, err = Render(ctx, "", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Count(FileUnchanged))
+
+	require.NoError(t, os.WriteFile(vpc, []byte("# edited\n"), 0644))
+	report, err = Render(ctx, "", "", true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Count(FileUnchanged))
+	require.Equal(t, 1, report.Count(FileSkipped))
+
+	var out bytes.Buffer
+	require.NoError(t, WriteGenerateReport(&out, report, utils.OutputJSON))
+	assert.Contains(t, out.String(), `"status": "skipped"`)
+	assert.Contains(t, out.String(), `"content": "include \"root\" {\n  path = \"../../../root.hcl\"\n}\n"`)
 
-	// Test with specific accountID
-	accountIDs, err = getManifestIdetifiers("account1.yaml", tempDir)
+	edited, err := os.ReadFile(vpc)
 	require.NoError(t, err)
-	assert.Equal(t, []string{"account1.yaml"}, accountIDs)
+	assert.Equal(t, "# edited\n", string(edited), "a dry run writes nothing")
 }
@@ -0,0 +1,26 @@
+include "root" {
+  path = "../../../root.hcl"
+}
+
+terraform {
+  source = "github.com/org/vpc?ref=1.0.0"
+}
+
+dependency "eks" {
+  config_path = "../eks"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+
+inputs = {
+  cluster_endpoint = dependency.eks.outputs.cluster_endpoint
+  private_subnets  = dependency.vpc.outputs.private_subnets
+  region           = "us-east-1"
+  tags = {
+    account_id = "789"
+    name       = "platform"
+  }
+  vpc_id = dependency.vpc.outputs.vpc_id
+}
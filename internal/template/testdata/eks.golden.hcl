@@ -0,0 +1,34 @@
+include "root" {
+  path = "../../../root.hcl"
+}
+
+terraform {
+  source = "github.com/org/eks?ref=2.0.0"
+}
+
+dependency "vpc" {
+  config_path  = "../vpc"
+  skip_outputs = false
+  mock_outputs = {
+    vpc_id = "vpc-123"
+  }
+}
+
+inputs = {
+  cluster_name = "platform"
+  name         = format("%s-eks", local.env)
+  node_groups = {
+    general = {
+      instance_types = ["m6i.large"]
+      max_size       = 3
+      min_size       = 1
+    }
+  }
+  private_subnets = dependency.vpc.outputs.private_subnets
+  region          = "us-east-1"
+  tags = {
+    account_id = "789"
+    name       = "platform"
+  }
+  vpc_id = dependency.vpc.outputs.vpc_id
+}
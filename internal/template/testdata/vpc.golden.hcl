@@ -0,0 +1,17 @@
+include "root" {
+  path = "../../../root.hcl"
+}
+
+terraform {
+  source = "github.com/org/vpc?ref=1.0.0"
+}
+
+inputs = {
+  azs    = ["us-east-1a", "us-east-1b"]
+  cidr   = "10.0.0.0/16"
+  region = "us-east-1"
+  tags = {
+    account_id = "789"
+    name       = "platform"
+  }
+}
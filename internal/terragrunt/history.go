@@ -0,0 +1,224 @@
+package terragrunt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+type (
+	// HistoryRecord is the audit record of one skiff run invocation. Manifest
+	// and Labels are the selectors of the run, Results the services they
+	// resolved to. Error is set when the run stopped before running any
+	// service, for example when a destroy was not confirmed.
+	HistoryRecord struct {
+		Time     time.Time `json:"time" yaml:"time"`
+		User     string    `json:"user" yaml:"user"`
+		Commit   string    `json:"commit,omitempty" yaml:"commit,omitempty"`
+		Command  string    `json:"command" yaml:"command"`
+		Args     []string  `json:"args,omitempty" yaml:"args,omitempty"`
+		Manifest string    `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+		Labels   string    `json:"labels,omitempty" yaml:"labels,omitempty"`
+		DryRun   bool      `json:"dry_run" yaml:"dry_run"`
+		Status   string    `json:"status" yaml:"status"`
+		Duration float64   `json:"duration_seconds" yaml:"duration_seconds"`
+		Error    string    `json:"error,omitempty" yaml:"error,omitempty"`
+		Results  []Result  `json:"results" yaml:"results"`
+	}
+
+	// HistoryQuery selects history records. Service matches either the
+	// service name or manifest/service, zero times leave the range open.
+	HistoryQuery struct {
+		Manifest string
+		Service  string
+		Since    time.Time
+		Until    time.Time
+	}
+)
+
+// NewHistoryRecord records a run of opts that started at start and ended
+// with report, or with runErr before any service ran.
+func NewHistoryRecord(opts RunOptions, report *Report, runErr error, start time.Time) HistoryRecord {
+	record := HistoryRecord{
+		Time:     start.UTC().Round(time.Second),
+		User:     currentUser(),
+		Commit:   currentCommit(),
+		Command:  opts.Command,
+		Args:     slices.DeleteFunc(slices.Clone(opts.Args), func(arg string) bool { return arg == "" }),
+		Manifest: opts.ManifestID,
+		Labels:   opts.Labels,
+		DryRun:   opts.DryRun,
+		Status:   StatusSucceeded,
+		Duration: time.Since(start).Round(time.Millisecond).Seconds(),
+		Results:  []Result{},
+	}
+
+	switch {
+	case runErr != nil:
+		record.Status, record.Error = StatusFailed, runErr.Error()
+	case report != nil:
+		record.Results = report.Results
+		if report.Failed() {
+			record.Status = StatusFailed
+		}
+	}
+	return record
+}
+
+// AppendHistory appends record to the JSONL history file at path, creating it
+// when needed.
+func AppendHistory(path string, record HistoryRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadHistory returns the records of the history file at path matching query,
+// oldest first. A missing file is an empty history.
+func ReadHistory(path string, query HistoryQuery) ([]HistoryRecord, error) {
+	records := []HistoryRecord{}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid history record: %w", path, line, err)
+		}
+		if query.matches(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	return records, nil
+}
+
+func (q HistoryQuery) matches(record HistoryRecord) bool {
+	if !q.Since.IsZero() && record.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && record.Time.After(q.Until) {
+		return false
+	}
+	if q.Manifest == "" && q.Service == "" {
+		return true
+	}
+
+	// a record without results still matches its manifest selector
+	if q.Service == "" && record.Manifest == q.Manifest {
+		return true
+	}
+	return slices.ContainsFunc(record.Results, func(r Result) bool {
+		if q.Manifest != "" && r.Manifest != q.Manifest {
+			return false
+		}
+		return q.Service == "" || r.Service == q.Service || r.Manifest+"/"+r.Service == q.Service
+	})
+}
+
+// ParseHistoryTime parses the bounds of a history query: an RFC 3339 time, a
+// date, or a duration such as 24h before now.
+func ParseHistoryTime(input string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, input); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, input, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(input); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use an RFC 3339 time, a date like 2006-01-02 or a duration like 24h", input)
+}
+
+// WriteHistory writes records in format, the text output as one line per run
+// listing its services.
+func WriteHistory(w io.Writer, records []HistoryRecord, format string) error {
+	return utils.WriteOutput(w, format, records, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tUSER\tCOMMAND\tSTATUS\tDURATION\tCOMMIT\tSERVICES")
+		for _, r := range records {
+			command := strings.TrimSpace(strings.Join(append([]string{r.Command}, r.Args...), " "))
+			if r.DryRun {
+				command += " (dry run)"
+			}
+			commit := r.Commit
+			if len(commit) > 8 {
+				commit = commit[:8]
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.1fs\t%s\t%s\n",
+				r.Time.Local().Format(time.DateTime), r.User, command, r.Status, r.Duration, commit, historyServices(r))
+		}
+		return tw.Flush()
+	})
+}
+
+// historyServices lists the services of a record, or its error when no
+// service ran.
+func historyServices(record HistoryRecord) string {
+	if len(record.Results) == 0 {
+		return record.Error
+	}
+	services := make([]string, 0, len(record.Results))
+	for _, r := range record.Results {
+		service := r.Manifest + "/" + r.Service
+		if r.Status == StatusFailed {
+			service += " (failed)"
+		}
+		services = append(services, service)
+	}
+	return strings.Join(services, ", ")
+}
+
+// currentUser returns the name of the user running skiff.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// currentCommit returns the git commit checked out in the working directory,
+// empty outside a repository.
+func currentCommit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,89 @@
+package terragrunt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	records, err := ReadHistory(path, HistoryQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	plan := NewHistoryRecord(RunOptions{Command: "plan", Args: []string{"-lock=false", ""}, ManifestID: "workload"}, &Report{
+		Results: []Result{
+			{Manifest: "workload", Service: "vpc", Status: StatusSucceeded},
+			{Manifest: "workload", Service: "eks", Status: StatusFailed, ExitCode: 1},
+		},
+	}, nil, start)
+	assert.Equal(t, StatusFailed, plan.Status)
+	assert.Equal(t, []string{"-lock=false"}, plan.Args)
+	assert.NotEmpty(t, plan.User)
+
+	destroy := NewHistoryRecord(RunOptions{Command: "destroy", Labels: "env=dev"}, nil, errors.New("not confirmed"), start.Add(48*time.Hour))
+	assert.Equal(t, StatusFailed, destroy.Status)
+	assert.Equal(t, "not confirmed", destroy.Error)
+
+	require.NoError(t, AppendHistory(path, plan))
+	require.NoError(t, AppendHistory(path, destroy))
+
+	commands := func(query HistoryQuery) []string {
+		records, err := ReadHistory(path, query)
+		require.NoError(t, err)
+		var commands []string
+		for _, r := range records {
+			commands = append(commands, r.Command)
+		}
+		return commands
+	}
+
+	assert.Equal(t, []string{"plan", "destroy"}, commands(HistoryQuery{}))
+	assert.Equal(t, []string{"plan"}, commands(HistoryQuery{Service: "eks"}))
+	assert.Equal(t, []string{"plan"}, commands(HistoryQuery{Service: "workload/vpc"}))
+	assert.Equal(t, []string{"plan"}, commands(HistoryQuery{Manifest: "workload"}))
+	assert.Empty(t, commands(HistoryQuery{Manifest: "other", Service: "eks"}))
+	assert.Equal(t, []string{"destroy"}, commands(HistoryQuery{Since: start.Add(time.Hour)}))
+	assert.Equal(t, []string{"plan"}, commands(HistoryQuery{Until: start.Add(time.Hour)}))
+
+	records, err = ReadHistory(path, HistoryQuery{})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, WriteHistory(&out, records, utils.OutputText))
+	assert.Contains(t, out.String(), "plan -lock=false")
+	assert.Contains(t, out.String(), "workload/vpc, workload/eks (failed)")
+	assert.Contains(t, out.String(), "not confirmed")
+
+	require.NoError(t, os.WriteFile(path, []byte("{}\nnot json\n"), 0644))
+	_, err = ReadHistory(path, HistoryQuery{})
+	assert.ErrorContains(t, err, "history.jsonl:2: invalid history record")
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	since, err := ParseHistoryTime("24h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), since)
+
+	since, err = ParseHistoryTime("2025-02-01T10:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC), since)
+
+	since, err = ParseHistoryTime("2025-02-01", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), since)
+
+	_, err = ParseHistoryTime("yesterday", now)
+	assert.ErrorContains(t, err, `invalid time "yesterday"`)
+}
@@ -0,0 +1,271 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// planFile is the file plans are saved to with -out unless the arguments of
+// the run set their own, relative to the service folder.
+const planFile = "skiff.tfplan"
+
+type (
+	// PlanChanges counts the resource changes of a plan. Destroys and
+	// Replaces list the addresses of the resources deleted outright and
+	// deleted to be created again.
+	PlanChanges struct {
+		Add      int      `json:"add" yaml:"add"`
+		Change   int      `json:"change" yaml:"change"`
+		Destroy  int      `json:"destroy" yaml:"destroy"`
+		Replace  int      `json:"replace" yaml:"replace"`
+		Destroys []string `json:"destroys,omitempty" yaml:"destroys,omitempty"`
+		Replaces []string `json:"replaces,omitempty" yaml:"replaces,omitempty"`
+	}
+
+	// planJSON is the part of the `terraform show -json` output of a plan
+	// the summary reads.
+	planJSON struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+)
+
+// ParsePlan counts the resource changes of a plan in the JSON format of
+// `terraform show -json`. No-op and read actions are not counted.
+func ParsePlan(data []byte) (*PlanChanges, error) {
+	var plan planJSON
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+
+	changes := &PlanChanges{}
+	for _, rc := range plan.ResourceChanges {
+		actions := rc.Change.Actions
+		switch {
+		case slices.Contains(actions, "delete") && slices.Contains(actions, "create"):
+			changes.Replace++
+			changes.Replaces = append(changes.Replaces, rc.Address)
+		case slices.Contains(actions, "delete"):
+			changes.Destroy++
+			changes.Destroys = append(changes.Destroys, rc.Address)
+		case slices.Contains(actions, "create"):
+			changes.Add++
+		case slices.Contains(actions, "update"):
+			changes.Change++
+		}
+	}
+	return changes, nil
+}
+
+// HasChanges reports whether the plan changes any resource.
+func (p *PlanChanges) HasChanges() bool {
+	return p.Add+p.Change+p.Destroy+p.Replace > 0
+}
+
+// planArgs returns the arguments of a plan of u saving it with -out, and the
+// file it is saved to: the -out of args, or planFile. Terragrunt runs terraform
+// in its cache folder, so a relative file is made absolute against the folder
+// of u. A plan left in the file by a previous run is removed so it is never
+// read back, or applied, in place of the new one.
+func planArgs(u unit, args []string) ([]string, string, error) {
+	file := planFile
+	args = slices.Clone(args)
+	for i := 0; i < len(args); i++ {
+		switch name, value, hasValue := strings.Cut(args[i], "="); {
+		case name != "-out" && name != "--out":
+			continue
+		case hasValue:
+			file = value
+			args = slices.Delete(args, i, i+1)
+		case i+1 < len(args):
+			file = args[i+1]
+			args = slices.Delete(args, i, i+2)
+		default:
+			return nil, "", fmt.Errorf("flag needs an argument: %s", args[i])
+		}
+		i--
+	}
+
+	if !filepath.IsAbs(file) {
+		dir, err := filepath.Abs(u.path)
+		if err != nil {
+			return nil, "", err
+		}
+		file = filepath.Join(dir, file)
+	}
+
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("failed to remove the previous plan %s: %w", file, err)
+	}
+	return append(args, "-out="+file), file, nil
+}
+
+// planService plans u with args and records the changes of the saved plan in
+// result. It returns the file the plan was saved to, empty when the plan
+// failed.
+func planService(ctx context.Context, u unit, args []string, output io.Writer, result *Result) string {
+	args, file, err := planArgs(u, args)
+	if err != nil {
+		result.Status, result.ExitCode, result.Error = StatusFailed, -1, err.Error()
+		return ""
+	}
+
+	runService(ctx, u, args, output, result)
+	if result.Status != StatusSucceeded {
+		return ""
+	}
+
+	if result.Plan, err = showPlan(ctx, u, file, output); err != nil {
+		result.Status, result.Error = StatusFailed, err.Error()
+		return ""
+	}
+	return file
+}
+
+// showPlan converts the plan of u saved to file with `show -json` and counts
+// its changes. It runs like the plan, under the same timeout and interrupt.
+func showPlan(ctx context.Context, u unit, file string, output io.Writer) (*PlanChanges, error) {
+	var stdout bytes.Buffer
+	cmd, cmdCtx, cancel := newCommand(ctx, u, "show", "-json", file)
+	defer cancel()
+	cmd.Stdout = &stdout
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		switch {
+		case ctx.Err() != nil:
+			return nil, errors.New("interrupted")
+		case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+			return nil, fmt.Errorf("timed out after %s showing the plan", u.policy.timeout)
+		}
+		return nil, fmt.Errorf("failed to show the plan: %w", err)
+	}
+
+	// terragrunt may log before the JSON document
+	data := stdout.Bytes()
+	if start := bytes.IndexByte(data, '{'); start > 0 {
+		data = data[start:]
+	}
+	return ParsePlan(data)
+}
+
+// PlanTotals sums the plans of the report per manifest, in the order the
+// manifests ran.
+func (r *Report) PlanTotals() ([]string, map[string]PlanChanges) {
+	var manifests []string
+	totals := map[string]PlanChanges{}
+	for _, result := range r.Results {
+		if result.Plan == nil {
+			continue
+		}
+		total, seen := totals[result.Manifest]
+		if !seen {
+			manifests = append(manifests, result.Manifest)
+		}
+		total.Add += result.Plan.Add
+		total.Change += result.Plan.Change
+		total.Destroy += result.Plan.Destroy
+		total.Replace += result.Plan.Replace
+		totals[result.Manifest] = total
+	}
+	return manifests, totals
+}
+
+// hasPlans reports whether a service of the report saved a plan summary.
+func (r *Report) hasPlans() bool {
+	return slices.ContainsFunc(r.Results, func(result Result) bool { return result.Plan != nil })
+}
+
+// writePlanSummary writes the changes of every planned service, the totals
+// per manifest and, separately, every resource the plans destroy.
+func writePlanSummary(w io.Writer, report *Report) error {
+	fmt.Fprintln(w, "\nPlan summary:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MANIFEST\tSERVICE\tADD\tCHANGE\tDESTROY\tREPLACE")
+	for _, r := range report.Results {
+		if r.Plan != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", r.Manifest, r.Service, r.Plan.Add, r.Plan.Change, r.Plan.Destroy, r.Plan.Replace)
+		}
+	}
+	manifests, totals := report.PlanTotals()
+	for _, m := range manifests {
+		t := totals[m]
+		fmt.Fprintf(tw, "%s\t(total)\t%d\t%d\t%d\t%d\n", m, t.Add, t.Change, t.Destroy, t.Replace)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	destroys := planDestroys(report)
+	if len(destroys) > 0 {
+		fmt.Fprintln(w, "\n⚠️  Resources to destroy:")
+		for _, line := range destroys {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	return nil
+}
+
+// WritePlanMarkdown writes the plan summary of the report as Markdown, for
+// example for a pull request comment.
+func WritePlanMarkdown(w io.Writer, report *Report) error {
+	fmt.Fprintln(w, "## Plan summary")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Manifest | Service | Add | Change | Destroy | Replace |")
+	fmt.Fprintln(w, "| --- | --- | ---: | ---: | ---: | ---: |")
+	for _, r := range report.Results {
+		switch {
+		case r.Plan != nil:
+			fmt.Fprintf(w, "| %s | %s | %d | %d | %d | %d |\n", r.Manifest, r.Service, r.Plan.Add, r.Plan.Change, r.Plan.Destroy, r.Plan.Replace)
+		case r.Status == StatusFailed:
+			fmt.Fprintf(w, "| %s | %s | ❌ failed | | | |\n", r.Manifest, r.Service)
+		}
+	}
+	manifests, totals := report.PlanTotals()
+	for _, m := range manifests {
+		t := totals[m]
+		fmt.Fprintf(w, "| **%s** | **total** | **%d** | **%d** | **%d** | **%d** |\n", m, t.Add, t.Change, t.Destroy, t.Replace)
+	}
+
+	destroys := planDestroys(report)
+	if len(destroys) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "### ⚠️ Resources to destroy")
+		fmt.Fprintln(w)
+		for _, line := range destroys {
+			fmt.Fprintf(w, "- %s\n", line)
+		}
+	}
+	return nil
+}
+
+// planDestroys lists the resources the plans of the report delete, one line
+// per resource naming its service.
+func planDestroys(report *Report) []string {
+	var lines []string
+	for _, r := range report.Results {
+		if r.Plan == nil {
+			continue
+		}
+		for _, address := range r.Plan.Destroys {
+			lines = append(lines, fmt.Sprintf("%s/%s: %s", r.Manifest, r.Service, address))
+		}
+		for _, address := range r.Plan.Replaces {
+			lines = append(lines, fmt.Sprintf("%s/%s: %s (replace)", r.Manifest, r.Service, address))
+		}
+	}
+	return lines
+}
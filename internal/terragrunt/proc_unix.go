@@ -0,0 +1,21 @@
+//go:build !windows
+
+package terragrunt
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in its own process group, so an interrupt from the
+// terminal reaches skiff alone and is forwarded to terragrunt once.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interrupt asks terragrunt to stop the way Ctrl-C does, letting it release
+// the state lock.
+func interrupt(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(os.Interrupt)
+}
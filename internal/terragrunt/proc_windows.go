@@ -0,0 +1,15 @@
+//go:build windows
+
+package terragrunt
+
+import "os/exec"
+
+// detach leaves cmd in the console of skiff, Windows has no process groups
+// to separate.
+func detach(cmd *exec.Cmd) {}
+
+// interrupt stops terragrunt. Windows cannot deliver an interrupt to another
+// process, so it is killed.
+func interrupt(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,87 @@
+package terragrunt
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/nyambati/skiff/internal/config"
+)
+
+// defaultRetryDelay is the wait before the first retry when the run settings
+// do not set retry_delay.
+const defaultRetryDelay = 10 * time.Second
+
+// interruptGrace is how long terragrunt gets to stop, and release the state
+// lock, after it is interrupted before it is killed.
+var interruptGrace = time.Minute
+
+// runPolicy is the parsed run settings of a service.
+type runPolicy struct {
+	timeout  time.Duration
+	retries  int
+	delay    time.Duration
+	patterns []*regexp.Regexp
+}
+
+// newRunPolicy parses settings, nil settings run every service once without a
+// timeout.
+func newRunPolicy(settings *config.RunSettings) (runPolicy, error) {
+	policy := runPolicy{delay: defaultRetryDelay}
+	if settings == nil {
+		return policy, nil
+	}
+
+	var err error
+	if settings.Timeout != "" {
+		if policy.timeout, err = parsePositiveDuration(settings.Timeout); err != nil {
+			return policy, fmt.Errorf("invalid run timeout: %w", err)
+		}
+	}
+	if settings.RetryDelay != "" {
+		if policy.delay, err = parsePositiveDuration(settings.RetryDelay); err != nil {
+			return policy, fmt.Errorf("invalid run retry_delay: %w", err)
+		}
+	}
+	if settings.Retries != nil {
+		if *settings.Retries < 0 {
+			return policy, fmt.Errorf("invalid run retries %d, expected 0 or more", *settings.Retries)
+		}
+		policy.retries = *settings.Retries
+	}
+
+	patterns := settings.RetryOn
+	if len(patterns) == 0 {
+		patterns = config.DefaultRetryPatterns
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return policy, fmt.Errorf("invalid run retry_on pattern %q: %w", pattern, err)
+		}
+		policy.patterns = append(policy.patterns, re)
+	}
+	return policy, nil
+}
+
+// retryOn returns the pattern matching the output of a failed attempt, empty
+// when the failure is not worth a retry.
+func (p runPolicy) retryOn(output []byte) string {
+	for _, re := range p.patterns {
+		if re.Match(output) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+func parsePositiveDuration(input string) (time.Duration, error) {
+	d, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s is not a positive duration", input)
+	}
+	return d, nil
+}
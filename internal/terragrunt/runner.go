@@ -0,0 +1,438 @@
+package terragrunt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nyambati/skiff/internal/catalog"
+	"github.com/nyambati/skiff/internal/config"
+	skiff "github.com/nyambati/skiff/internal/errors"
+	"github.com/nyambati/skiff/internal/strategy"
+	"github.com/nyambati/skiff/internal/template"
+	"github.com/nyambati/skiff/internal/utils"
+)
+
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusSkipped is a service that was not run, because of a dry run or an
+	// earlier failure.
+	StatusSkipped = "skipped"
+)
+
+// terragruntBinary is the command run in every service folder.
+var terragruntBinary = "terragrunt"
+
+type (
+	// RunOptions selects the services of a run and the terragrunt command
+	// run in their folders. Output receives the output of terragrunt and the
+	// confirmation prompts, os.Stderr when nil. Input answers the prompts,
+	// os.Stdin when nil.
+	RunOptions struct {
+		Command    string
+		Args       []string
+		ManifestID string
+		Labels     string
+		DryRun     bool
+		// AllowDestroy allows destroy, and apply of plans deleting resources,
+		// once the manifest name is typed as confirmation.
+		AllowDestroy bool
+		// ForceProtected allows destroying resources of protected services.
+		ForceProtected bool
+		Output         io.Writer
+		Input          io.Reader
+	}
+
+	// Result is the outcome of running terragrunt for one service. Duration is
+	// in seconds.
+	Result struct {
+		Manifest string  `json:"manifest" yaml:"manifest"`
+		Service  string  `json:"service" yaml:"service"`
+		Path     string  `json:"path" yaml:"path"`
+		Status   string  `json:"status" yaml:"status"`
+		ExitCode int     `json:"exit_code" yaml:"exit_code"`
+		Duration float64 `json:"duration_seconds" yaml:"duration_seconds"`
+		Error    string  `json:"error,omitempty" yaml:"error,omitempty"`
+		// Attempts counts the terragrunt runs of the service, retries
+		// included.
+		Attempts int `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+		// Protected is set for the services protected from destroy.
+		Protected bool `json:"protected,omitempty" yaml:"protected,omitempty"`
+		// Plan holds the changes of a successful plan.
+		Plan *PlanChanges `json:"plan,omitempty" yaml:"plan,omitempty"`
+	}
+
+	// Report lists the results of a run in the order the services ran.
+	Report struct {
+		Command string   `json:"command" yaml:"command"`
+		Args    []string `json:"args,omitempty" yaml:"args,omitempty"`
+		DryRun  bool     `json:"dry_run" yaml:"dry_run"`
+		Results []Result `json:"results" yaml:"results"`
+	}
+
+	// unit is a service folder terragrunt runs in.
+	unit struct {
+		manifest     string
+		service      string
+		path         string
+		dependencies []string
+		protected    bool
+		policy       runPolicy
+	}
+
+	// destroyGuard asks for the confirmations of a run destroying resources,
+	// once per manifest.
+	destroyGuard struct {
+		opts      RunOptions
+		input     *bufio.Reader
+		output    io.Writer
+		confirmed map[string]bool
+	}
+)
+
+// RunServices runs terragrunt with the command and args of opts in the folder
+// of every selected service, one service at a time. Services of a manifest run
+// after the services they depend on, and before them for destroy. The first
+// failure skips the services left, except for plan which changes nothing.
+// Plans are saved with -out and read back with `show -json` to count their
+// changes. With DryRun every service is reported as skipped without running.
+//
+// Destroy runs only with AllowDestroy, after the name of every manifest is
+// typed as confirmation, and protected services also need ForceProtected. The
+// confirmed destroy runs with -auto-approve.
+// Apply plans every service first and applies the saved plan, under the same
+// rules when the plan deletes resources.
+//
+// Every service runs under its run settings, see config.RunSettings. When ctx
+// is cancelled the running terragrunt is interrupted and the services left
+// are skipped.
+func RunServices(ctx context.Context, opts RunOptions) (*Report, error) {
+	configs, err := template.GetRenderConfig(ctx, opts.ManifestID, opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	units, err := runOrder(*configs, opts.Command == "destroy")
+	if err != nil {
+		return nil, err
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	guard := newDestroyGuard(opts, output)
+	if opts.Command == "destroy" && !opts.DryRun {
+		if err := guard.check(opts.Command, units); err != nil {
+			return nil, err
+		}
+	}
+
+	args := slices.DeleteFunc(slices.Clone(opts.Args), func(arg string) bool { return arg == "" })
+	report := &Report{Command: opts.Command, Args: args, DryRun: opts.DryRun, Results: make([]Result, 0, len(units))}
+
+	commandArgs := append([]string{opts.Command}, args...)
+	if opts.Command == "destroy" && !slices.Contains(commandArgs, "-auto-approve") {
+		// confirmed by the guard, terragrunt has no terminal to prompt on
+		commandArgs = append(commandArgs, "-auto-approve")
+	}
+
+	failed := false
+	for _, u := range units {
+		result := Result{Manifest: u.manifest, Service: u.service, Path: u.path, Status: StatusSkipped, Protected: u.protected}
+
+		switch {
+		case opts.DryRun:
+		case ctx.Err() != nil:
+			result.Error = "not run, the run was interrupted"
+		case failed:
+			result.Error = "not run, a previous service failed"
+		case opts.Command == "plan":
+			planService(ctx, u, commandArgs, output, &result)
+		case opts.Command == "apply":
+			applyService(ctx, u, args, output, guard, &result)
+			failed = result.Status == StatusFailed
+		default:
+			runService(ctx, u, commandArgs, output, &result)
+			failed = result.Status == StatusFailed
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// applyService plans u with args, then applies the saved plan, the file of
+// their -out when set. A plan deleting
+// resources is only applied once guard allows it.
+func applyService(ctx context.Context, u unit, args []string, output io.Writer, guard *destroyGuard, result *Result) {
+	// the saved plan is applied without a prompt
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "-auto-approve" })
+	file := planService(ctx, u, append([]string{"plan"}, args...), output, result)
+	if file == "" {
+		return
+	}
+
+	if result.Plan.Destroy+result.Plan.Replace > 0 {
+		if err := guard.check("apply", []unit{u}); err != nil {
+			result.Status, result.Error = StatusFailed, err.Error()
+			return
+		}
+	}
+
+	runService(ctx, u, []string{"apply", file}, output, result)
+}
+
+// runService runs terragrunt with args in the folder of u and records the
+// exit code and duration in result. A failed attempt whose output matches a
+// retry pattern of the policy of u is retried, waiting twice as long before
+// every retry. Timeouts and interrupts are not retried.
+func runService(ctx context.Context, u unit, args []string, output io.Writer, result *Result) {
+	if _, err := os.Stat(u.path); err != nil {
+		result.Status, result.ExitCode = StatusFailed, -1
+		result.Error = fmt.Sprintf("%s is not generated, run skiff generate first", u.path)
+		return
+	}
+
+	fmt.Fprintf(output, "🏃 %s/%s: %s %s\n", u.manifest, u.service, terragruntBinary, strings.Join(args, " "))
+
+	delay := u.policy.delay
+	for {
+		result.Attempts++
+		captured, err := runAttempt(ctx, u, args, output, result)
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			result.Status, result.ExitCode, result.Error = StatusSucceeded, 0, ""
+			return
+		case ctx.Err() != nil:
+			result.Status, result.ExitCode = StatusFailed, -1
+			result.Error = "interrupted"
+			if errors.As(err, &exitErr) {
+				result.ExitCode = exitErr.ExitCode()
+			}
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			result.Status, result.ExitCode = StatusFailed, -1
+			result.Error = fmt.Sprintf("timed out after %s", u.policy.timeout)
+			return
+		case errors.As(err, &exitErr):
+			result.Status, result.ExitCode = StatusFailed, exitErr.ExitCode()
+			result.Error = err.Error()
+		default:
+			result.Status, result.ExitCode = StatusFailed, -1
+			result.Error = err.Error()
+			return
+		}
+
+		pattern := u.policy.retryOn(captured)
+		if result.Attempts > u.policy.retries || pattern == "" {
+			return
+		}
+
+		fmt.Fprintf(output, "🔁 %s/%s: attempt %d failed on %q, retrying in %s\n", u.manifest, u.service, result.Attempts, pattern, delay)
+		select {
+		case <-ctx.Done():
+			result.Error = "interrupted"
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// runAttempt runs terragrunt with args in the folder of u once, within the
+// timeout of its policy. When ctx is cancelled or the timeout expires
+// terragrunt is interrupted and given interruptGrace to release the state
+// lock. The duration of the attempt is added to result. It returns the output
+// of the attempt when it may be retried, and context.DeadlineExceeded after a
+// timeout.
+func runAttempt(ctx context.Context, u unit, args []string, output io.Writer, result *Result) ([]byte, error) {
+	var captured bytes.Buffer
+	if u.policy.retries > 0 {
+		output = io.MultiWriter(output, &captured)
+	}
+
+	cmd, cmdCtx, cancel := newCommand(ctx, u, args...)
+	defer cancel()
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration += time.Since(start).Round(time.Millisecond).Seconds()
+
+	if err != nil && ctx.Err() == nil && errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		return captured.Bytes(), context.DeadlineExceeded
+	}
+	return captured.Bytes(), err
+}
+
+// newCommand prepares terragrunt with args in the folder of u, under the
+// timeout of its policy. When ctx is cancelled or the timeout expires,
+// terragrunt is interrupted and killed after interruptGrace. The returned
+// context ends with the command, cancel releases it.
+func newCommand(ctx context.Context, u unit, args ...string) (*exec.Cmd, context.Context, context.CancelFunc) {
+	cmdCtx, cancel := ctx, context.CancelFunc(func() {})
+	if u.policy.timeout > 0 {
+		cmdCtx, cancel = context.WithTimeout(ctx, u.policy.timeout)
+	}
+
+	cmd := exec.CommandContext(cmdCtx, terragruntBinary, args...)
+	cmd.Dir = u.path
+	cmd.Cancel = func() error { return interrupt(cmd) }
+	cmd.WaitDelay = interruptGrace
+	detach(cmd)
+	return cmd, cmdCtx, cancel
+}
+
+// runOrder returns the services of configs, leaving out the level files, so
+// that every service of a manifest comes after the selected services it
+// depends on, or before them when reverse is set. Services without an order
+// between them keep the order of configs.
+func runOrder(configs strategy.RenderConfig, reverse bool) ([]unit, error) {
+	var units []unit
+	for _, cfg := range configs {
+		if cfg.Level != "" {
+			continue
+		}
+
+		policy, err := newRunPolicy(cfg.Run)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", cfg.Manifest, cfg.Service, err)
+		}
+
+		u := unit{manifest: cfg.Manifest, service: cfg.Service, path: cfg.TargetFolder, protected: cfg.Protected, policy: policy}
+		if cfg.Context != nil {
+			dependencies, _ := (*cfg.Context)[config.DependencyKey].([]catalog.Dependency)
+			for _, dep := range dependencies {
+				if name, ok := dep[config.ServiceKey].(string); ok {
+					u.dependencies = append(u.dependencies, name)
+				}
+			}
+		}
+		units = append(units, u)
+	}
+
+	key := func(manifest, service string) string { return manifest + "/" + service }
+	selected := map[string]bool{}
+	for _, u := range units {
+		selected[key(u.manifest, u.service)] = true
+	}
+
+	ordered := make([]unit, 0, len(units))
+	done := map[string]bool{}
+	for len(ordered) < len(units) {
+		progress := false
+		for _, u := range units {
+			if done[key(u.manifest, u.service)] {
+				continue
+			}
+			ready := !slices.ContainsFunc(u.dependencies, func(dep string) bool {
+				return selected[key(u.manifest, dep)] && !done[key(u.manifest, dep)]
+			})
+			if ready {
+				ordered = append(ordered, u)
+				done[key(u.manifest, u.service)] = true
+				progress = true
+			}
+		}
+
+		if !progress {
+			var cycle []string
+			for _, u := range units {
+				if !done[key(u.manifest, u.service)] {
+					cycle = append(cycle, key(u.manifest, u.service))
+				}
+			}
+			return nil, fmt.Errorf("services depend on each other in a cycle: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	if reverse {
+		slices.Reverse(ordered)
+	}
+	return ordered, nil
+}
+
+func newDestroyGuard(opts RunOptions, output io.Writer) *destroyGuard {
+	input := opts.Input
+	if input == nil {
+		input = os.Stdin
+	}
+	return &destroyGuard{opts: opts, input: bufio.NewReader(input), output: output, confirmed: map[string]bool{}}
+}
+
+// check returns an error unless command may destroy resources of units:
+// destroying needs AllowDestroy, protected services need ForceProtected and
+// the name of every manifest is typed as confirmation.
+func (g *destroyGuard) check(command string, units []unit) error {
+	var services, protected []string
+	for _, u := range units {
+		services = append(services, u.manifest+"/"+u.service)
+		if u.protected {
+			protected = append(protected, u.manifest+"/"+u.service)
+		}
+	}
+
+	if !g.opts.AllowDestroy {
+		return skiff.NewDestroyNotAllowedError(command, services...)
+	}
+	if len(protected) > 0 && !g.opts.ForceProtected {
+		return skiff.NewProtectedServiceError(command, protected...)
+	}
+
+	for _, u := range units {
+		if g.confirmed[u.manifest] {
+			continue
+		}
+		prompt := fmt.Sprintf("⚠️  %s deletes resources of manifest %s, type its name to confirm: ", command, u.manifest)
+		if !utils.ConfirmTyped(g.input, g.output, prompt, u.manifest) {
+			return skiff.NewConfirmationError(u.manifest)
+		}
+		g.confirmed[u.manifest] = true
+	}
+	return nil
+}
+
+// Failed reports whether a service of the report failed.
+func (r *Report) Failed() bool {
+	return slices.ContainsFunc(r.Results, func(result Result) bool { return result.Status == StatusFailed })
+}
+
+// WriteReport writes the report in format, the text output as one line per
+// service followed by the plan summary of a plan.
+func WriteReport(w io.Writer, report *Report, format string) error {
+	return utils.WriteOutput(w, format, report, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MANIFEST\tSERVICE\tSTATUS\tEXIT CODE\tDURATION\tPATH")
+		for _, r := range report.Results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.1fs\t%s\n", r.Manifest, r.Service, r.Status, r.ExitCode, r.Duration, r.Path)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		for _, r := range report.Results {
+			if r.Status == StatusFailed {
+				fmt.Fprintf(w, "❌ %s/%s: %s\n", r.Manifest, r.Service, r.Error)
+			}
+		}
+
+		if report.hasPlans() {
+			return writePlanSummary(w, report)
+		}
+		return nil
+	})
+}
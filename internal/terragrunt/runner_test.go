@@ -0,0 +1,434 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nyambati/skiff/internal/config"
+	"github.com/nyambati/skiff/internal/template"
+	"github.com/nyambati/skiff/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRun generates a project with dns depending on eks depending on vpc and
+// replaces terragrunt with a script logging the folder and arguments of
+// every call. The script fails in folders holding a file named fail, fails
+// once with a state lock error in folders holding a file named flaky, sleeps
+// in folders holding a file named slow, and shows the plan.json of the folder
+// as the saved plan.
+func setupRun(t *testing.T) (context.Context, *config.Config, string) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Path: config.Path{
+			Manifests:  filepath.Join(tempDir, "manifests"),
+			Templates:  filepath.Join(tempDir, "templates"),
+			Terragrunt: filepath.Join(tempDir, "terragrunt"),
+		},
+		Strategy: config.Strategy{Template: "{{ var.region }}/{{ var.service }}"},
+	}
+
+	files := map[string]string{
+		"manifests/catalog.yaml": "types:\n  mod:\n    source: github.com/org/mod\n    version: 1.0.0\n",
+		"manifests/workload.yaml": `services:
+  dns:
+    type: mod
+    region: us-east-1
+    dependencies:
+      - service: eks
+  eks:
+    type: mod
+    region: us-east-1
+    dependencies:
+      - service: vpc
+  vpc:
+    type: mod
+    region: us-east-1
+    labels:
+      tier: network
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	ctx := context.WithValue(context.Background(), "config", cfg)
+	_, err := template.Render(ctx, "", "", false)
+	require.NoError(t, err)
+
+	log := filepath.Join(tempDir, "calls.log")
+	script := filepath.Join(tempDir, "terragrunt.sh")
+	require.NoError(t, os.WriteFile(script, []byte(fmt.Sprintf(`#!/bin/sh
+if [ "$1" = show ]; then
+  test ! -f slow-show || exec sleep 5
+  exec cat "$3"
+fi
+echo "$(basename "$PWD") $*" | sed "s|$PWD/||g" >> %s
+echo "output of $*"
+if [ -f flaky ]; then
+  rm flaky
+  echo "Error acquiring the state lock"
+  exit 1
+fi
+test ! -f slow || exec sleep 5
+test ! -f fail || exit 1
+for arg; do
+  case "$arg" in
+  -out=*) cat plan.json 2>/dev/null > "${arg#-out=}" || echo '{"resource_changes": []}' > "${arg#-out=}" ;;
+  esac
+done
+`, log)), 0755))
+
+	previous := terragruntBinary
+	terragruntBinary = script
+	t.Cleanup(func() { terragruntBinary = previous })
+	return ctx, cfg, log
+}
+
+func calls(t *testing.T, log string) []string {
+	content, err := os.ReadFile(log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func TestRunServices(t *testing.T) {
+	t.Run("Services run after their dependencies", func(t *testing.T) {
+		ctx, _, log := setupRun(t)
+		var output bytes.Buffer
+
+		report, err := RunServices(ctx, RunOptions{Command: "plan", Args: []string{"-lock=false", ""}, Output: &output})
+		require.NoError(t, err)
+		assert.False(t, report.Failed())
+		assert.Equal(t, []string{
+			"vpc plan -lock=false -out=skiff.tfplan",
+			"eks plan -lock=false -out=skiff.tfplan",
+			"dns plan -lock=false -out=skiff.tfplan",
+		}, calls(t, log))
+		assert.Contains(t, output.String(), "output of plan -lock=false -out=/")
+
+		for _, result := range report.Results {
+			assert.Equal(t, StatusSucceeded, result.Status)
+			assert.Equal(t, 0, result.ExitCode)
+		}
+	})
+
+	t.Run("Destroy runs in reverse order", func(t *testing.T) {
+		ctx, _, log := setupRun(t)
+
+		_, err := RunServices(ctx, RunOptions{
+			Command:      "destroy",
+			AllowDestroy: true,
+			Output:       &bytes.Buffer{},
+			Input:        strings.NewReader("workload\n"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dns destroy -auto-approve", "eks destroy -auto-approve", "vpc destroy -auto-approve"}, calls(t, log))
+	})
+
+	t.Run("Destroy keeps an explicit -auto-approve", func(t *testing.T) {
+		ctx, _, log := setupRun(t)
+
+		report, err := RunServices(ctx, RunOptions{
+			Command:      "destroy",
+			Args:         []string{"-auto-approve", "-lock=false"},
+			Labels:       "tier=network",
+			AllowDestroy: true,
+			Output:       &bytes.Buffer{},
+			Input:        strings.NewReader("workload\n"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"-auto-approve", "-lock=false"}, report.Args)
+		assert.Equal(t, []string{"vpc destroy -auto-approve -lock=false"}, calls(t, log))
+	})
+
+	t.Run("A failure skips the services left", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "eks", "fail"), nil, 0644))
+
+		report, err := RunServices(ctx, RunOptions{Command: "apply", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.True(t, report.Failed())
+		assert.Equal(t, []string{"vpc plan -out=skiff.tfplan", "vpc apply skiff.tfplan", "eks plan -out=skiff.tfplan"}, calls(t, log))
+
+		assert.Equal(t, StatusSucceeded, report.Results[0].Status)
+		assert.Equal(t, StatusFailed, report.Results[1].Status)
+		assert.Equal(t, 1, report.Results[1].ExitCode)
+		assert.Equal(t, StatusSkipped, report.Results[2].Status)
+
+		var out bytes.Buffer
+		require.NoError(t, WriteReport(&out, report, utils.OutputText))
+		assert.Contains(t, out.String(), "❌ workload/eks: exit status 1\n")
+	})
+
+	t.Run("Plans continue after a failure", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "eks", "fail"), nil, 0644))
+
+		report, err := RunServices(ctx, RunOptions{Command: "plan", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.True(t, report.Failed())
+		assert.Len(t, calls(t, log), 3)
+	})
+
+	t.Run("Plans are saved to -out", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		vpc := filepath.Join(cfg.Terragrunt, "us-east-1", "vpc")
+		fixture, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(vpc, "plan.json"), fixture, 0644))
+
+		report, err := RunServices(ctx, RunOptions{
+			Command:      "apply",
+			Args:         []string{"-out=x"},
+			Labels:       "tier=network",
+			AllowDestroy: true,
+			Output:       &bytes.Buffer{},
+			Input:        strings.NewReader("workload\n"),
+		})
+		require.NoError(t, err)
+		assert.False(t, report.Failed())
+		assert.Equal(t, []string{"vpc plan -out=x", "vpc apply x"}, calls(t, log))
+		assert.Equal(t, []string{"-out=x"}, report.Args)
+		require.NotNil(t, report.Results[0].Plan)
+		assert.True(t, report.Results[0].Plan.HasChanges())
+		assert.FileExists(t, filepath.Join(vpc, "x"))
+		assert.NoFileExists(t, filepath.Join(vpc, planFile))
+	})
+
+	t.Run("A previous plan is never applied", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		vpc := filepath.Join(cfg.Terragrunt, "us-east-1", "vpc")
+		require.NoError(t, os.WriteFile(filepath.Join(vpc, planFile), []byte("{}"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(vpc, "fail"), nil, 0644))
+
+		report, err := RunServices(ctx, RunOptions{Command: "apply", Labels: "tier=network", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.True(t, report.Failed())
+		assert.Equal(t, []string{"vpc plan -out=skiff.tfplan"}, calls(t, log))
+		assert.NoFileExists(t, filepath.Join(vpc, planFile))
+	})
+
+	t.Run("Dry run runs nothing", func(t *testing.T) {
+		ctx, _, log := setupRun(t)
+
+		report, err := RunServices(ctx, RunOptions{Command: "apply", Labels: "", DryRun: true, Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Empty(t, calls(t, log))
+		assert.Len(t, report.Results, 3)
+
+		var out bytes.Buffer
+		require.NoError(t, WriteReport(&out, report, utils.OutputJSON))
+		assert.Contains(t, out.String(), `"status": "skipped"`)
+		assert.Contains(t, out.String(), `"exit_code": 0`)
+	})
+}
+
+func TestRunRetries(t *testing.T) {
+	retries := 1
+
+	t.Run("Matching failures are retried", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		cfg.Run = &config.RunSettings{Retries: &retries, RetryDelay: "1ms"}
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "vpc", "flaky"), nil, 0644))
+		var output bytes.Buffer
+
+		report, err := RunServices(ctx, RunOptions{Command: "plan", Output: &output})
+		require.NoError(t, err)
+		assert.False(t, report.Failed())
+		assert.Equal(t, 2, report.Results[0].Attempts)
+		assert.Equal(t, 1, report.Results[1].Attempts)
+		assert.Contains(t, output.String(), `🔁 workload/vpc: attempt 1 failed on "Error acquiring the state lock", retrying in 1ms`)
+		assert.Len(t, calls(t, log), 4)
+	})
+
+	t.Run("Other failures are not retried", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		cfg.Run = &config.RunSettings{Retries: &retries, RetryDelay: "1ms", RetryOn: []string{"Rate exceeded"}}
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "vpc", "flaky"), nil, 0644))
+
+		report, err := RunServices(ctx, RunOptions{Command: "apply", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, report.Results[0].Status)
+		assert.Equal(t, 1, report.Results[0].Attempts)
+		assert.Equal(t, []string{"vpc plan -out=skiff.tfplan"}, calls(t, log))
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		ctx, cfg, _ := setupRun(t)
+		cfg.Run = &config.RunSettings{Timeout: "soon"}
+
+		_, err := RunServices(ctx, RunOptions{Command: "plan", Output: &bytes.Buffer{}})
+		assert.ErrorContains(t, err, `workload/dns: invalid run timeout: time: invalid duration "soon"`)
+	})
+}
+
+func TestRunInterrupts(t *testing.T) {
+	previous := interruptGrace
+	interruptGrace = 100 * time.Millisecond
+	t.Cleanup(func() { interruptGrace = previous })
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cfg, _ := setupRun(t)
+		cfg.Run = &config.RunSettings{Timeout: "200ms"}
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "eks", "slow"), nil, 0644))
+
+		report, err := RunServices(ctx, RunOptions{Command: "plan", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, report.Results[1].Status)
+		assert.Equal(t, "timed out after 200ms", report.Results[1].Error)
+		assert.Equal(t, StatusSucceeded, report.Results[2].Status)
+	})
+
+	t.Run("Timeout showing the plan", func(t *testing.T) {
+		ctx, cfg, _ := setupRun(t)
+		cfg.Run = &config.RunSettings{Timeout: "200ms"}
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "vpc", "slow-show"), nil, 0644))
+
+		start := time.Now()
+		report, err := RunServices(ctx, RunOptions{Command: "apply", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.Equal(t, StatusFailed, report.Results[0].Status)
+		assert.Equal(t, "timed out after 200ms showing the plan", report.Results[0].Error)
+		assert.Equal(t, StatusSkipped, report.Results[1].Status)
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "eks", "slow"), nil, 0644))
+		ctx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		start := time.Now()
+		report, err := RunServices(ctx, RunOptions{Command: "plan", Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.Equal(t, StatusSucceeded, report.Results[0].Status)
+		assert.Equal(t, "interrupted", report.Results[1].Error)
+		assert.Equal(t, StatusSkipped, report.Results[2].Status)
+		assert.Equal(t, "not run, the run was interrupted", report.Results[2].Error)
+		assert.Len(t, calls(t, log), 2)
+	})
+}
+
+func TestRunDestroyGuards(t *testing.T) {
+	t.Run("Destroy needs --allow-destroy", func(t *testing.T) {
+		ctx, _, log := setupRun(t)
+
+		_, err := RunServices(ctx, RunOptions{Command: "destroy", Output: &bytes.Buffer{}})
+		assert.EqualError(t, err, "destroy of workload/dns, workload/eks, workload/vpc deletes resources, rerun with --allow-destroy to allow it")
+		assert.Empty(t, calls(t, log))
+	})
+
+	t.Run("Destroy needs the manifest name", func(t *testing.T) {
+		ctx, _, log := setupRun(t)
+		var output bytes.Buffer
+
+		_, err := RunServices(ctx, RunOptions{Command: "destroy", AllowDestroy: true, Output: &output, Input: strings.NewReader("yes\n")})
+		assert.EqualError(t, err, "confirmation did not match manifest workload, nothing was destroyed")
+		assert.Contains(t, output.String(), "destroy deletes resources of manifest workload, type its name to confirm: ")
+		assert.Empty(t, calls(t, log))
+	})
+
+	t.Run("Protected services need --force-protected", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		cfg.Protected = []string{"tier=network"}
+
+		opts := RunOptions{Command: "destroy", AllowDestroy: true, Output: &bytes.Buffer{}, Input: strings.NewReader("workload\n")}
+		_, err := RunServices(ctx, opts)
+		assert.EqualError(t, err, "refusing to destroy protected services workload/vpc, rerun with --force-protected to allow it")
+		assert.Empty(t, calls(t, log))
+
+		opts.ForceProtected = true
+		report, err := RunServices(ctx, opts)
+		require.NoError(t, err)
+		assert.False(t, report.Failed())
+		assert.True(t, report.Results[2].Protected)
+		assert.Equal(t, []string{"dns destroy -auto-approve", "eks destroy -auto-approve", "vpc destroy -auto-approve"}, calls(t, log))
+	})
+
+	t.Run("Apply of a plan deleting resources", func(t *testing.T) {
+		ctx, cfg, log := setupRun(t)
+		fixture, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "eks", "plan.json"), fixture, 0644))
+
+		report, err := RunServices(ctx, RunOptions{Command: "apply", Args: []string{"-auto-approve"}, Output: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, report.Results[1].Status)
+		assert.Equal(t, "apply of workload/eks deletes resources, rerun with --allow-destroy to allow it", report.Results[1].Error)
+		assert.Equal(t, StatusSkipped, report.Results[2].Status)
+		assert.Equal(t, []string{"vpc plan -out=skiff.tfplan", "vpc apply skiff.tfplan", "eks plan -out=skiff.tfplan"}, calls(t, log))
+
+		var output bytes.Buffer
+		report, err = RunServices(ctx, RunOptions{Command: "apply", AllowDestroy: true, Output: &output, Input: strings.NewReader("workload\n")})
+		require.NoError(t, err)
+		assert.False(t, report.Failed())
+		assert.Equal(t, 1, strings.Count(output.String(), "type its name to confirm"))
+		assert.Contains(t, calls(t, log), "eks apply skiff.tfplan")
+	})
+}
+
+func TestRunPlanSummary(t *testing.T) {
+	ctx, cfg, _ := setupRun(t)
+	fixture, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Terragrunt, "us-east-1", "eks", "plan.json"), fixture, 0644))
+
+	report, err := RunServices(ctx, RunOptions{Command: "plan", Output: &bytes.Buffer{}})
+	require.NoError(t, err)
+	require.False(t, report.Failed())
+
+	assert.Equal(t, &PlanChanges{}, report.Results[0].Plan)
+	assert.Equal(t, &PlanChanges{
+		Add:      1,
+		Change:   1,
+		Destroy:  1,
+		Replace:  2,
+		Destroys: []string{"aws_iam_role.legacy"},
+		Replaces: []string{"aws_eks_node_group.general", "aws_security_group.cluster"},
+	}, report.Results[1].Plan)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteReport(&out, report, utils.OutputText))
+	assert.Contains(t, out.String(), "workload  (total)  1    1       1        2\n")
+	assert.Contains(t, out.String(), "⚠️  Resources to destroy:\n  workload/eks: aws_iam_role.legacy\n  workload/eks: aws_eks_node_group.general (replace)\n")
+
+	var markdown bytes.Buffer
+	require.NoError(t, WritePlanMarkdown(&markdown, report))
+	assert.Equal(t, `## Plan summary
+
+| Manifest | Service | Add | Change | Destroy | Replace |
+| --- | --- | ---: | ---: | ---: | ---: |
+| workload | vpc | 0 | 0 | 0 | 0 |
+| workload | eks | 1 | 1 | 1 | 2 |
+| workload | dns | 0 | 0 | 0 | 0 |
+| **workload** | **total** | **1** | **1** | **1** | **2** |
+
+### ⚠️ Resources to destroy
+
+- workload/eks: aws_iam_role.legacy
+- workload/eks: aws_eks_node_group.general (replace)
+- workload/eks: aws_security_group.cluster (replace)
+`, markdown.String())
+}
+
+func TestParsePlan(t *testing.T) {
+	_, err := ParsePlan([]byte("not json"))
+	assert.ErrorContains(t, err, "failed to parse plan JSON")
+
+	changes, err := ParsePlan([]byte(`{"resource_changes": [{"address": "a.b", "change": {"actions": ["no-op"]}}]}`))
+	require.NoError(t, err)
+	assert.False(t, changes.HasChanges())
+}
@@ -0,0 +1,153 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"gopkg.in/yaml.v3"
+)
+
+// ExpressionTag marks a YAML scalar as an HCL expression.
+const ExpressionTag = "!expr"
+
+// ExpressionKey is the only key of a mapping that declares an expression, the
+// form to use where YAML tags are not available, such as JSON.
+const ExpressionKey = "expr"
+
+// Expression is a raw HCL expression, such as dependency.vpc.outputs.vpc_id
+// or a function call, written to the generated configuration as is instead of
+// as a quoted string. Manifests declare one with the !expr tag or as a
+// mapping with a single expr key.
+type Expression string
+
+// Validate checks that the expression is valid HCL.
+func (e Expression) Validate() error {
+	if _, diags := hclsyntax.ParseExpression([]byte(e), "", hcl.InitialPos); diags.HasErrors() {
+		return fmt.Errorf("invalid expression %q: %s", string(e), diags.Error())
+	}
+	return nil
+}
+
+// MarshalYAML writes the expression back with its tag.
+func (e Expression) MarshalYAML() (any, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: ExpressionTag, Value: string(e)}, nil
+}
+
+// UnmarshalYAML reads a tagged scalar or an expr mapping.
+func (e *Expression) UnmarshalYAML(node *yaml.Node) error {
+	value, err := DecodeValue(node)
+	if err != nil {
+		return err
+	}
+
+	expr, ok := value.(Expression)
+	if !ok {
+		return fmt.Errorf("line %d: expected an expression", node.Line)
+	}
+	*e = expr
+	return nil
+}
+
+// Values is a map of decoded YAML values, such as the inputs of a service,
+// that keeps the expressions it holds.
+type Values map[string]any
+
+// UnmarshalYAML decodes a mapping with DecodeValue.
+func (v *Values) UnmarshalYAML(node *yaml.Node) error {
+	value, err := DecodeValue(node)
+	if err != nil {
+		return err
+	}
+
+	values, ok := value.(map[string]any)
+	if !ok && value != nil {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	*v = values
+	return nil
+}
+
+// DecodeValue decodes a YAML node the way yaml.v3 decodes into an any value,
+// except that expressions are kept as Expression values. Invalid expressions
+// are reported with their line.
+func DecodeValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return DecodeValue(node.Content[0])
+
+	case yaml.AliasNode:
+		return DecodeValue(node.Alias)
+
+	case yaml.ScalarNode:
+		if node.Tag == ExpressionTag {
+			return decodeExpression(node)
+		}
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+
+	case yaml.SequenceNode:
+		values := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := DecodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+
+	case yaml.MappingNode:
+		if len(node.Content) == 2 && node.Content[0].Value == ExpressionKey && node.Content[1].Kind == yaml.ScalarNode {
+			return decodeExpression(node.Content[1])
+		}
+
+		values := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := DecodeValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			values[node.Content[i].Value] = value
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+func decodeExpression(node *yaml.Node) (Expression, error) {
+	expr := Expression(node.Value)
+	if err := expr.Validate(); err != nil {
+		return "", fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return expr, nil
+}
+
+// ContainsExpression reports whether value is, or holds at any depth, an
+// Expression.
+func ContainsExpression(value any) bool {
+	switch v := value.(type) {
+	case Expression:
+		return true
+	case map[string]any:
+		for _, item := range v {
+			if ContainsExpression(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if ContainsExpression(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDecodeValue(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    any
+		expectError bool
+	}{
+		{
+			name:     "Tagged scalar",
+			input:    `!expr dependency.vpc.outputs.vpc_id`,
+			expected: Expression("dependency.vpc.outputs.vpc_id"),
+		},
+		{
+			name:     "Expr mapping",
+			input:    `{expr: "merge(local.tags, {team = \"data\"})"}`,
+			expected: Expression(`merge(local.tags, {team = "data"})`),
+		},
+		{
+			name: "Nested in lists and maps",
+			input: `
+subnets:
+  - !expr dependency.vpc.outputs.private_subnets[0]
+  - 10.0.1.0/24
+name: __literal
+count: 3
+`,
+			expected: map[string]any{
+				"subnets": []any{Expression("dependency.vpc.outputs.private_subnets[0]"), "10.0.1.0/24"},
+				"name":    "__literal",
+				"count":   3,
+			},
+		},
+		{
+			name:        "Invalid expression",
+			input:       `!expr dependency.vpc.outputs.(`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(tc.input), &node))
+
+			value, err := DecodeValue(&node)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, value)
+		})
+	}
+}
+
+func TestValuesRoundTrip(t *testing.T) {
+	values := Values{"vpc_id": Expression("dependency.vpc.outputs.vpc_id"), "name": "vpc"}
+
+	out, err := yaml.Marshal(values)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "vpc_id: !expr dependency.vpc.outputs.vpc_id")
+
+	var decoded Values
+	require.NoError(t, yaml.Unmarshal(out, &decoded))
+	assert.Equal(t, values, decoded)
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputText  = "text"
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// WriteOutput writes v to w in the requested format. JSON and YAML are
+// encoded from v directly, text output, also called table, is delegated to
+// table so each command controls its own columns.
+func WriteOutput(w io.Writer, format string, v any, table func(w io.Writer) error) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case OutputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(v)
+	case OutputText, OutputTable, "":
+		return table(w)
+	default:
+		return ValidateOutput(format)
+	}
+}
+
+// ValidateOutput returns an error unless format is a supported output format.
+func ValidateOutput(format string) error {
+	switch format {
+	case OutputText, OutputTable, OutputJSON, OutputYAML, "":
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, expected one of %s, %s or %s", format, OutputText, OutputJSON, OutputYAML)
+	}
+}
@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nyambati/skiff/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseSetFlag splits a `path=value` flag into its dotted path and a value.
+// Only true, false and integers written without leading zeros are typed, a
+// value tagged !expr becomes an expression and anything else, such as an
+// account ID with a leading zero, a version like 1.10 or a date, is kept as
+// the string given. Other typed values are set with --set-json.
+func ParseSetFlag(input string) (string, any, error) {
+	path, raw, err := splitPathValue(input)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch {
+	case raw == "true" || raw == "false":
+		return path, raw == "true", nil
+	case isCanonicalInt(raw):
+		n, _ := strconv.Atoi(raw)
+		return path, n, nil
+	case !strings.HasPrefix(raw, "!expr"):
+		return path, raw, nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &node); err != nil {
+		return "", nil, fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+
+	value, err := types.DecodeValue(&node)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+	return path, value, nil
+}
+
+// isCanonicalInt reports whether raw is an integer that reads back the same,
+// so 10 is an integer while 010, +10 and 1e3 are not.
+func isCanonicalInt(raw string) bool {
+	n, err := strconv.Atoi(raw)
+	return err == nil && strconv.Itoa(n) == raw
+}
+
+// ParseSetJSONFlag splits a `path=<json>` flag into its dotted path and the
+// decoded JSON value. An object whose only key is expr, such as
+// {"expr": "dependency.vpc.outputs.vpc_id"}, decodes to an expression.
+func ParseSetJSONFlag(input string) (string, any, error) {
+	path, raw, err := splitPathValue(input)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !json.Valid([]byte(raw)) {
+		return "", nil, fmt.Errorf("invalid JSON value for %s: %s", path, raw)
+	}
+
+	// JSON is valid YAML, decoding it as such keeps integers as integers
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &node); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON value for %s: %w", path, err)
+	}
+
+	value, err := types.DecodeValue(&node)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid JSON value for %s: %w", path, err)
+	}
+	return path, value, nil
+}
+
+func splitPathValue(input string) (string, string, error) {
+	kv := strings.SplitN(input, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return "", "", fmt.Errorf("invalid value %q, expected path=value", input)
+	}
+	return strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]), nil
+}
+
+// SetValueAtPath sets value at the dotted path inside data, creating
+// intermediate maps as needed. Numeric segments index into existing lists.
+func SetValueAtPath(data map[string]any, path string, value any) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	var current any = data
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		switch node := current.(type) {
+		case map[string]any:
+			if last {
+				node[segment] = value
+				return nil
+			}
+			next, ok := node[segment]
+			if !ok || next == nil {
+				next = map[string]any{}
+				node[segment] = next
+			}
+			current = next
+
+		case []any:
+			idx, err := listIndex(node, segment, path)
+			if err != nil {
+				return err
+			}
+			if last {
+				node[idx] = value
+				return nil
+			}
+			current = node[idx]
+
+		default:
+			return fmt.Errorf("cannot set %s: %s is not a map or list", path, strings.Join(segments[:i], "."))
+		}
+	}
+	return nil
+}
+
+// UnsetValueAtPath removes the value at the dotted path inside data. Removing
+// a path that does not exist is not an error.
+func UnsetValueAtPath(data map[string]any, path string) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	var current any = data
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		switch node := current.(type) {
+		case map[string]any:
+			if last {
+				delete(node, segment)
+				return nil
+			}
+			current = node[segment]
+
+		case []any:
+			idx, err := listIndex(node, segment, path)
+			if err != nil {
+				return err
+			}
+			if last {
+				return fmt.Errorf("cannot unset %s: removing list elements is not supported, use --set-json", path)
+			}
+			current = node[idx]
+
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func splitPath(path string) ([]string, error) {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", path)
+		}
+	}
+	return segments, nil
+}
+
+func listIndex(list []any, segment, path string) (int, error) {
+	idx, err := strconv.Atoi(segment)
+	if err != nil {
+		return 0, fmt.Errorf("invalid path %s: %q is not a list index", path, segment)
+	}
+	if idx < 0 || idx >= len(list) {
+		return 0, fmt.Errorf("invalid path %s: index %d out of range", path, idx)
+	}
+	return idx, nil
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/nyambati/skiff/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSetFlag(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		expectedPath  string
+		expectedValue any
+		expectError   bool
+	}{
+		{name: "String value", input: "inputs.cidr=10.0.0.0/16", expectedPath: "inputs.cidr", expectedValue: "10.0.0.0/16"},
+		{name: "Integer value", input: "inputs.count=3", expectedPath: "inputs.count", expectedValue: 3},
+		{name: "Boolean value", input: "inputs.enabled=true", expectedPath: "inputs.enabled", expectedValue: true},
+		{name: "Empty value", input: "labels.team=", expectedPath: "labels.team", expectedValue: ""},
+		{name: "Account ID with a leading zero", input: "metadata.account_id=012345678901", expectedPath: "metadata.account_id", expectedValue: "012345678901"},
+		{name: "Version", input: "version=1.10", expectedPath: "version", expectedValue: "1.10"},
+		{name: "File mode", input: "inputs.mode=0755", expectedPath: "inputs.mode", expectedValue: "0755"},
+		{name: "Date", input: "labels.since=2024-01-01", expectedPath: "labels.since", expectedValue: "2024-01-01"},
+		{name: "Null stays a string", input: "labels.team=null", expectedPath: "labels.team", expectedValue: "null"},
+		{name: "Flow list stays a string", input: "inputs.azs=[a,b]", expectedPath: "inputs.azs", expectedValue: "[a,b]"},
+		{name: "Expression", input: "inputs.vpc_id=!expr dependency.vpc.outputs.vpc_id", expectedPath: "inputs.vpc_id", expectedValue: types.Expression("dependency.vpc.outputs.vpc_id")},
+		{name: "Invalid expression", input: "inputs.vpc_id=!expr dependency.(", expectError: true},
+		{name: "Missing value", input: "inputs.cidr", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path, value, err := ParseSetFlag(tc.input)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, path)
+			assert.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
+
+func TestParseSetJSONFlag(t *testing.T) {
+	path, value, err := ParseSetJSONFlag(`inputs.subnets=[{"cidr":"10.0.1.0/24","size":24}]`)
+	require.NoError(t, err)
+	assert.Equal(t, "inputs.subnets", path)
+	assert.Equal(t, []any{map[string]any{"cidr": "10.0.1.0/24", "size": 24}}, value)
+
+	_, value, err = ParseSetJSONFlag(`inputs.vpc_id={"expr":"dependency.vpc.outputs.vpc_id"}`)
+	require.NoError(t, err)
+	assert.Equal(t, types.Expression("dependency.vpc.outputs.vpc_id"), value)
+
+	_, _, err = ParseSetJSONFlag(`inputs.subnets=[not json`)
+	assert.Error(t, err)
+}
+
+func TestSetValueAtPath(t *testing.T) {
+	data := map[string]any{
+		"inputs": map[string]any{
+			"subnets": []any{map[string]any{"cidr": "10.0.1.0/24"}},
+		},
+	}
+
+	require.NoError(t, SetValueAtPath(data, "inputs.cidr", "10.0.0.0/16"))
+	require.NoError(t, SetValueAtPath(data, "labels.team", "platform"))
+	require.NoError(t, SetValueAtPath(data, "inputs.subnets.0.cidr", "10.0.2.0/24"))
+
+	assert.Equal(t, map[string]any{
+		"inputs": map[string]any{
+			"cidr":    "10.0.0.0/16",
+			"subnets": []any{map[string]any{"cidr": "10.0.2.0/24"}},
+		},
+		"labels": map[string]any{"team": "platform"},
+	}, data)
+
+	assert.Error(t, SetValueAtPath(data, "inputs.cidr.mask", 16))
+	assert.Error(t, SetValueAtPath(data, "inputs.subnets.3.cidr", "x"))
+	assert.Error(t, SetValueAtPath(data, "inputs..cidr", "x"))
+}
+
+func TestUnsetValueAtPath(t *testing.T) {
+	data := map[string]any{
+		"labels": map[string]any{"team": "platform", "env": "prod"},
+	}
+
+	require.NoError(t, UnsetValueAtPath(data, "labels.team"))
+	require.NoError(t, UnsetValueAtPath(data, "labels.missing"))
+	require.NoError(t, UnsetValueAtPath(data, "inputs.cidr"))
+
+	assert.Equal(t, map[string]any{"labels": map[string]any{"env": "prod"}}, data)
+}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -42,6 +44,37 @@ func WriteFile(path string, content []byte) error {
 	return nil
 }
 
+// WriteFileAtomic writes content to path through a temporary file in the same
+// folder that is renamed over path once complete, so readers and failures
+// never see a partially written file.
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // ParseKeyValueFlag parses a comma-separated string of key-value pairs into a map.
 // The string is split by commas, and then each pair is split by the first equals sign.
 // Leading and trailing whitespace on the keys and values is trimmed.
@@ -144,15 +177,6 @@ func PrintErrorAndExit(err error) {
 	os.Exit(1)
 }
 
-func HasLabels(source, labels map[string]any) bool {
-	for key, val := range labels {
-		if source[key] != val {
-			return false
-		}
-	}
-	return true
-}
-
 func ToMap[T any](input T) (map[string]any, error) {
 	data, err := json.Marshal(input)
 	if err != nil {
@@ -186,6 +210,37 @@ func SanitizePath(input string) string {
 	return strings.Join(cleanParts, "/")
 }
 
+// invalidPathChars are rejected in target paths, they are not portable across
+// the common filesystems.
+const invalidPathChars = `<>:"\|?*`
+
+// ValidatePath checks that a path sanitized by SanitizePath, which is never
+// absolute, stays inside the folder it is joined to and only uses portable
+// characters. It returns the reason the path was rejected, or an empty string.
+func ValidatePath(path string) string {
+	if path == "" {
+		return "path is empty"
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case ".", "..":
+			return fmt.Sprintf("segment %q escapes the output folder", segment)
+		}
+
+		for _, r := range segment {
+			if r < 0x20 || r == 0x7f || strings.ContainsRune(invalidPathChars, r) {
+				return fmt.Sprintf("segment %q contains invalid character %q", segment, r)
+			}
+		}
+
+		if strings.HasSuffix(segment, ".") {
+			return fmt.Sprintf("segment %q must not end with a dot", segment)
+		}
+	}
+	return ""
+}
+
 func findEditor() (editor string, args []string) {
 	if editor := os.Getenv("VISUAL"); editor != "" {
 		parts := strings.Fields(editor)
@@ -299,6 +354,18 @@ func FromYAML[T any](data []byte) (*T, error) {
 	return &inter, nil
 }
 
+// FromYAMLStrict decodes data into T and fails on keys that T does not
+// define, catching typos in user supplied paths and files.
+func FromYAMLStrict[T any](data []byte) (*T, error) {
+	var inter T
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&inter); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &inter, nil
+}
+
 func PrependWatermark(content, toolName string) []byte {
 	watermark := fmt.Sprintf(`# This configuration generated and managed by %s. DO NOT EDIT.
 # Last updated at: %s
@@ -313,7 +380,7 @@ func PrependWatermark(content, toolName string) []byte {
 func ShouldWrite(oldContent, newContent []byte) bool {
 	// Check if there's any diff
 	if bytes.Equal(oldContent, newContent) {
-		fmt.Println("✅ No changes detected.")
+		fmt.Fprintln(os.Stderr, "✅ No changes detected.")
 		return true
 	}
 
@@ -322,7 +389,7 @@ func ShouldWrite(oldContent, newContent []byte) bool {
 	printUnifiedYAMLDiff(string(oldContent), string(newContent))
 
 	// Prompt user
-	fmt.Print("Do you accept these changes? (y/N): ")
+	fmt.Fprint(os.Stderr, "Do you accept these changes? (y/N): ")
 	var answer string
 	fmt.Scan(&answer)
 
@@ -334,6 +401,35 @@ func ShouldWrite(oldContent, newContent []byte) bool {
 	return true
 }
 
+// ConfirmChanges works like ShouldWrite but skips the prompt when assumeYes is
+// set, so edits can be applied from pipelines. The diff is still printed.
+func ConfirmChanges(oldContent, newContent []byte, assumeYes bool) bool {
+	if !assumeYes {
+		return ShouldWrite(oldContent, newContent)
+	}
+
+	if bytes.Equal(oldContent, newContent) {
+		fmt.Fprintln(os.Stderr, "✅ No changes detected.")
+		return true
+	}
+
+	logrus.Println("changes detected, showing diff:")
+	printUnifiedYAMLDiff(string(oldContent), string(newContent))
+	return true
+}
+
+// ConfirmTyped writes prompt to out and reports whether the next line read
+// from in is expected, for confirmations that take more than a y.
+func ConfirmTyped(in *bufio.Reader, out io.Writer, prompt, expected string) bool {
+	fmt.Fprint(out, prompt)
+	answer, err := in.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(out)
+		return false
+	}
+	return strings.TrimSpace(answer) == expected
+}
+
 func printUnifiedYAMLDiff(oldContent, newContent string) {
 	diff := difflib.UnifiedDiff{
 		A:        difflib.SplitLines(oldContent),
@@ -345,7 +441,7 @@ func printUnifiedYAMLDiff(oldContent, newContent string) {
 
 	text, err := difflib.GetUnifiedDiffString(diff)
 	if err != nil {
-		fmt.Println("failed to compute diff:", err)
+		fmt.Fprintln(os.Stderr, "failed to compute diff:", err)
 		return
 	}
 
@@ -353,13 +449,13 @@ func printUnifiedYAMLDiff(oldContent, newContent string) {
 	for _, line := range strings.Split(text, "\n") {
 		switch {
 		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
-			fmt.Printf("\033[32m%s\033[0m\n", line) // green
+			fmt.Fprintf(os.Stderr, "\033[32m%s\033[0m\n", line) // green
 		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
-			fmt.Printf("\033[31m%s\033[0m\n", line) // red
+			fmt.Fprintf(os.Stderr, "\033[31m%s\033[0m\n", line) // red
 		case strings.HasPrefix(line, "@@"):
-			fmt.Printf("\033[36m%s\033[0m\n", line) // cyan
+			fmt.Fprintf(os.Stderr, "\033[36m%s\033[0m\n", line) // cyan
 		default:
-			fmt.Println(line)
+			fmt.Fprintln(os.Stderr, line)
 		}
 	}
 }
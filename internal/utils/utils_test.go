@@ -50,6 +50,34 @@ func TestWriteFile(t *testing.T) {
 	})
 }
 
+func TestWriteFileAtomic(t *testing.T) {
+	t.Run("Replace File", func(t *testing.T) {
+		tempDir := t.TempDir()
+		filePath := filepath.Join(tempDir, "terragrunt.hcl")
+		require.NoError(t, os.WriteFile(filePath, []byte("old"), 0600))
+
+		require.NoError(t, WriteFileAtomic(filePath, []byte("new"), 0644))
+
+		readContent, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(readContent))
+
+		info, err := os.Stat(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+		// no temporary file is left behind
+		entries, err := os.ReadDir(tempDir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Missing Directory", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "missing", "terragrunt.hcl")
+		assert.Error(t, WriteFileAtomic(filePath, []byte("content"), 0644))
+	})
+}
+
 func TestParseKeyValueFlag(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -131,3 +159,26 @@ func TestToMap(t *testing.T) {
 		assert.Equal(t, map[string]any{"Value": "test"}, result)
 	})
 }
+
+func TestValidatePath(t *testing.T) {
+	testCases := []struct {
+		name   string
+		path   string
+		reason string
+	}{
+		{name: "Valid path", path: "123456789012/us-east-1/vpc"},
+		{name: "Dots inside a segment", path: "services/app.v2/vpc"},
+		{name: "Empty path", path: "", reason: "path is empty"},
+		{name: "Parent segment", path: "prod/../../etc", reason: `segment ".." escapes the output folder`},
+		{name: "Current segment", path: "./vpc", reason: `segment "." escapes the output folder`},
+		{name: "Invalid character", path: "prod/vpc:main", reason: `segment "vpc:main" contains invalid character ':'`},
+		{name: "Control character", path: "prod/vpc\x00", reason: `segment "vpc\x00" contains invalid character '\x00'`},
+		{name: "Trailing dot", path: "prod/vpc.", reason: `segment "vpc." must not end with a dot`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reason, ValidatePath(tc.path))
+		})
+	}
+}
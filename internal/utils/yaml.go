@@ -0,0 +1,334 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument is a parsed YAML document kept alongside its original lines so
+// that individual mapping entries can be rewritten without re-encoding the
+// rest of the file.
+type yamlDocument struct {
+	raw    []byte
+	root   *yaml.Node
+	lines  []string
+	indent int
+}
+
+// yamlEntry records a key/value pair of a block mapping and the first line
+// (0-based, exclusive) that no longer belongs to it.
+type yamlEntry struct {
+	mapping *yaml.Node
+	index   int
+	end     int
+}
+
+// SetYAMLValue stores value under the mapping keys given by path and returns
+// the updated document. Only the lines holding the addressed entry are
+// rewritten; comments, key order and formatting of every other entry are kept
+// byte-for-byte. Missing intermediate mappings are created.
+func SetYAMLValue(doc []byte, value any, path ...string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("yaml path must not be empty")
+	}
+
+	node := new(yaml.Node)
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return editYAML(doc, node, path)
+}
+
+// DeleteYAMLValue removes the entry addressed by path from the document,
+// together with the comment lines directly above it. Deleting a key that does
+// not exist returns the document unchanged.
+func DeleteYAMLValue(doc []byte, path ...string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("yaml path must not be empty")
+	}
+	return editYAML(doc, nil, path)
+}
+
+// editYAML sets (value != nil) or deletes (value == nil) the entry at path.
+func editYAML(doc []byte, value *yaml.Node, path []string) ([]byte, error) {
+	d, err := parseYAMLDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.root == nil {
+		if value == nil {
+			return doc, nil
+		}
+		return d.appendDocument(editYAMLNode(nil, path, value))
+	}
+
+	var stack []yamlEntry
+	node, end := d.root, len(d.lines)
+
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+			// Flow mappings and empty or scalar values cannot be spliced
+			// line by line, rewrite the closest enclosing entry instead.
+			return d.rebuild(stack, path[i:], value)
+		}
+
+		idx := findYAMLKey(node, key)
+		if idx < 0 {
+			if value == nil {
+				return d.raw, nil
+			}
+			return d.insert(node, end, key, editYAMLNode(nil, path[i+1:], value))
+		}
+
+		if idx+2 < len(node.Content) {
+			end = d.entryStart(node.Content[idx+2])
+		}
+
+		stack = append(stack, yamlEntry{mapping: node, index: idx, end: end})
+		node = node.Content[idx+1]
+	}
+
+	entry := stack[len(stack)-1]
+	if value != nil {
+		return d.replace(entry, value)
+	}
+
+	if len(entry.mapping.Content) == 2 && len(stack) > 1 {
+		// Keep the parent key around as an empty mapping rather than
+		// turning it into null.
+		parent := stack[len(stack)-2]
+		return d.replace(parent, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle})
+	}
+
+	return d.remove(entry), nil
+}
+
+func parseYAMLDocument(doc []byte) (*yamlDocument, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+
+	raw := doc
+	if len(raw) > 0 && !bytes.HasSuffix(raw, []byte("\n")) {
+		raw = append(append([]byte{}, raw...), '\n')
+	}
+
+	lines := strings.SplitAfter(string(raw), "\n")
+	d := &yamlDocument{
+		raw:    raw,
+		lines:  lines[:len(lines)-1],
+		indent: 2,
+	}
+
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 && root.Content[0].Kind == yaml.MappingNode {
+		d.root = root.Content[0]
+		if indent := detectYAMLIndent(d.root); indent > 0 {
+			d.indent = indent
+		}
+	} else if root.Kind == yaml.DocumentNode && len(root.Content) > 0 && root.Content[0].Tag != "!!null" {
+		return nil, fmt.Errorf("yaml document root is not a mapping")
+	}
+
+	return d, nil
+}
+
+// detectYAMLIndent returns the indentation used by the first nested block
+// mapping in the document, or 0 if there is none.
+func detectYAMLIndent(node *yaml.Node) int {
+	if node.Kind != yaml.MappingNode {
+		return 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, val := node.Content[i], node.Content[i+1]
+		if val.Kind == yaml.MappingNode && val.Style&yaml.FlowStyle == 0 && len(val.Content) > 0 {
+			if indent := val.Content[0].Column - key.Column; indent > 0 {
+				return indent
+			}
+		}
+		if indent := detectYAMLIndent(val); indent > 0 {
+			return indent
+		}
+	}
+	return 0
+}
+
+func findYAMLKey(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// editYAMLNode returns node with value set (or removed when value is nil) at
+// path, converting scalars and flow mappings along the way into block mappings.
+func editYAMLNode(node *yaml.Node, path []string, value *yaml.Node) *yaml.Node {
+	if len(path) == 0 {
+		return value
+	}
+
+	if node == nil || node.Kind != yaml.MappingNode {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	node.Style &^= yaml.FlowStyle
+
+	idx := findYAMLKey(node, path[0])
+	switch {
+	case idx >= 0 && value == nil && len(path) == 1:
+		node.Content = append(node.Content[:idx], node.Content[idx+2:]...)
+	case idx >= 0:
+		node.Content[idx+1] = editYAMLNode(node.Content[idx+1], path[1:], value)
+	case value != nil:
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+		node.Content = append(node.Content, key, editYAMLNode(nil, path[1:], value))
+	}
+
+	if len(node.Content) == 0 {
+		node.Style = yaml.FlowStyle
+	}
+	return node
+}
+
+// rebuild rewrites the innermost entry on the stack with path applied to its
+// value. When the stack is empty the whole document is re-encoded.
+func (d *yamlDocument) rebuild(stack []yamlEntry, path []string, value *yaml.Node) ([]byte, error) {
+	if len(stack) == 0 {
+		root := editYAMLNode(d.root, path, value)
+		return d.encode(root, 0)
+	}
+
+	entry := stack[len(stack)-1]
+	current := entry.mapping.Content[entry.index+1]
+	if value == nil && (current.Kind != yaml.MappingNode || findYAMLKey(current, path[0]) < 0) {
+		return d.raw, nil
+	}
+
+	return d.replace(entry, editYAMLNode(current, path, value))
+}
+
+// replace swaps the lines of entry for a freshly encoded key/value pair.
+func (d *yamlDocument) replace(entry yamlEntry, value *yaml.Node) ([]byte, error) {
+	key := entry.mapping.Content[entry.index]
+	start := key.Line - 1
+	end := d.trimEnd(start, entry.end)
+
+	text, err := d.encodeEntry(key, value, key.Column-1)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.splice(start, end, text), nil
+}
+
+// insert appends a new key/value pair after the last entry of mapping.
+func (d *yamlDocument) insert(mapping *yaml.Node, end int, key string, value *yaml.Node) ([]byte, error) {
+	last := mapping.Content[len(mapping.Content)-2]
+	at := d.trimEnd(last.Line-1, end)
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	text, err := d.encodeEntry(keyNode, value, last.Column-1)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.splice(at, at, text), nil
+}
+
+// remove drops the lines of entry, including the comment block directly
+// above its key.
+func (d *yamlDocument) remove(entry yamlEntry) []byte {
+	key := entry.mapping.Content[entry.index]
+	start := d.entryStart(key)
+	end := d.trimEnd(key.Line-1, entry.end)
+	return d.splice(start, end, "")
+}
+
+func (d *yamlDocument) appendDocument(root *yaml.Node) ([]byte, error) {
+	text, err := d.encode(root, 0)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, d.raw...), text...), nil
+}
+
+// entryStart returns the line on which the entry owning key starts, which
+// includes any comment lines immediately above the key.
+func (d *yamlDocument) entryStart(key *yaml.Node) int {
+	start := key.Line - 1
+	for start > 0 && isYAMLComment(d.lines[start-1]) {
+		start--
+	}
+	return start
+}
+
+// trimEnd moves end back over trailing blank and comment lines so they stay in
+// place when the entry starting at start is rewritten.
+func (d *yamlDocument) trimEnd(start, end int) int {
+	for end > start+1 && (isYAMLComment(d.lines[end-1]) || strings.TrimSpace(d.lines[end-1]) == "") {
+		end--
+	}
+	return end
+}
+
+func (d *yamlDocument) splice(start, end int, text string) []byte {
+	var buf bytes.Buffer
+	for _, line := range d.lines[:start] {
+		buf.WriteString(line)
+	}
+	buf.WriteString(text)
+	for _, line := range d.lines[end:] {
+		buf.WriteString(line)
+	}
+	return buf.Bytes()
+}
+
+func (d *yamlDocument) encodeEntry(key, value *yaml.Node, column int) (string, error) {
+	k := *key
+	k.HeadComment, k.FootComment = "", ""
+
+	entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&k, value}}
+	text, err := d.encode(entry, column)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// encode marshals node with the document indentation and shifts every line by
+// column spaces.
+func (d *yamlDocument) encode(node *yaml.Node, column int) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	if column == 0 {
+		return buf.Bytes(), nil
+	}
+
+	prefix := strings.Repeat(" ", column)
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if strings.TrimSpace(line) != "" {
+			out.WriteString(prefix)
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes(), nil
+}
+
+func isYAMLComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
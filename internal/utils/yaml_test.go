@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDocument = `# header comment
+
+apiVersion: v1
+metadata:
+  # the account
+  account_id: "123456789012"
+  env: production
+services:
+  # networking
+  vpc:
+    type: vpc
+    inputs:
+      cidr: 10.0.0.0/16 # primary range
+
+  # databases
+  rds:
+    type: rds
+`
+
+func TestSetYAMLValue(t *testing.T) {
+	testCases := []struct {
+		name     string
+		doc      string
+		path     []string
+		value    any
+		expected string
+	}{
+		{
+			name:  "Replace entry in the middle of a mapping",
+			doc:   testDocument,
+			path:  []string{"services", "vpc"},
+			value: map[string]any{"type": "vpc", "region": "eu-west-1"},
+			expected: `# header comment
+
+apiVersion: v1
+metadata:
+  # the account
+  account_id: "123456789012"
+  env: production
+services:
+  # networking
+  vpc:
+    region: eu-west-1
+    type: vpc
+
+  # databases
+  rds:
+    type: rds
+`,
+		},
+		{
+			name:  "Replace last entry of the document",
+			doc:   testDocument,
+			path:  []string{"services", "rds"},
+			value: map[string]any{"type": "aurora"},
+			expected: `# header comment
+
+apiVersion: v1
+metadata:
+  # the account
+  account_id: "123456789012"
+  env: production
+services:
+  # networking
+  vpc:
+    type: vpc
+    inputs:
+      cidr: 10.0.0.0/16 # primary range
+
+  # databases
+  rds:
+    type: aurora
+`,
+		},
+		{
+			name:  "Insert new entry after the last one",
+			doc:   testDocument,
+			path:  []string{"metadata", "team"},
+			value: "platform",
+			expected: `# header comment
+
+apiVersion: v1
+metadata:
+  # the account
+  account_id: "123456789012"
+  env: production
+  team: platform
+services:
+  # networking
+  vpc:
+    type: vpc
+    inputs:
+      cidr: 10.0.0.0/16 # primary range
+
+  # databases
+  rds:
+    type: rds
+`,
+		},
+		{
+			name:  "Insert into empty flow mapping",
+			doc:   "apiVersion: v1\nservices: {}\n",
+			path:  []string{"services", "vpc"},
+			value: map[string]any{"type": "vpc"},
+			expected: `apiVersion: v1
+services:
+  vpc:
+    type: vpc
+`,
+		},
+		{
+			name:     "Create missing parent mapping",
+			doc:      "apiVersion: v1\n",
+			path:     []string{"services", "vpc", "type"},
+			value:    "vpc",
+			expected: "apiVersion: v1\nservices:\n  vpc:\n    type: vpc\n",
+		},
+		{
+			name:     "Empty document",
+			doc:      "# only a comment\n",
+			path:     []string{"types", "vpc"},
+			value:    map[string]any{"version": "1.0.0"},
+			expected: "# only a comment\ntypes:\n  vpc:\n    version: 1.0.0\n",
+		},
+		{
+			name:     "Respects document indentation",
+			doc:      "services:\n    vpc:\n        type: vpc\n",
+			path:     []string{"services", "rds"},
+			value:    map[string]any{"type": "rds"},
+			expected: "services:\n    vpc:\n        type: vpc\n    rds:\n        type: rds\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := SetYAMLValue([]byte(tc.doc), tc.value, tc.path...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(result))
+		})
+	}
+}
+
+func TestDeleteYAMLValue(t *testing.T) {
+	testCases := []struct {
+		name     string
+		doc      string
+		path     []string
+		expected string
+	}{
+		{
+			name: "Delete entry with its comment",
+			doc:  testDocument,
+			path: []string{"services", "vpc"},
+			expected: `# header comment
+
+apiVersion: v1
+metadata:
+  # the account
+  account_id: "123456789012"
+  env: production
+services:
+
+  # databases
+  rds:
+    type: rds
+`,
+		},
+		{
+			name:     "Delete last entry keeps empty mapping",
+			doc:      "services:\n  vpc:\n    type: vpc\n",
+			path:     []string{"services", "vpc"},
+			expected: "services: {}\n",
+		},
+		{
+			name:     "Delete missing key is a no-op",
+			doc:      testDocument,
+			path:     []string{"services", "missing"},
+			expected: testDocument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := DeleteYAMLValue([]byte(tc.doc), tc.path...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(result))
+		})
+	}
+}